@@ -2,16 +2,25 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/fatih/color"
+	"github.com/tyuhara/yamlcmt/internal/ci"
 	"github.com/tyuhara/yamlcmt/internal/config"
 	"github.com/tyuhara/yamlcmt/internal/diff"
 	"github.com/tyuhara/yamlcmt/internal/git"
 	"github.com/tyuhara/yamlcmt/internal/github"
+	"github.com/tyuhara/yamlcmt/internal/gitlab"
 	"github.com/tyuhara/yamlcmt/internal/parser"
+	"github.com/tyuhara/yamlcmt/internal/pathfilter"
+	"github.com/tyuhara/yamlcmt/internal/vcs"
 )
 
 var (
@@ -29,27 +38,67 @@ type CLI struct {
 }
 
 type CompareCmd struct {
-	File1      string `arg:"" optional:"" help:"First YAML file to compare (optional with --git-compare)." type:"existingfile"`
-	File2      string `arg:"" optional:"" help:"Second YAML file to compare (optional with --git-compare)." type:"existingfile"`
-	Key        string `help:"YAML path to use as document identifier." default:"metadata.name"`
-	ShowCounts bool   `short:"c" help:"Show summary counts only."`
-	Verbose    bool   `short:"v" help:"Show verbose output with full document content."`
-	NoColor    bool   `help:"Disable color output."`
+	File1      string            `arg:"" optional:"" help:"First YAML file or directory to compare (optional with --git-compare)." type:"path"`
+	File2      string            `arg:"" optional:"" help:"Second YAML file or directory to compare (optional with --git-compare)." type:"path"`
+	Key        []string          `help:"YAML path(s) to use as document identifier, comma-separated (or the \"k8s\" preset for apiVersion/kind/namespace/name)." default:"metadata.name"`
+	ListKey    map[string]string `help:"Merge key used to match list elements at a path (path=field, e.g. spec.template.spec.containers=name)."`
+	StrictKeys bool              `help:"Fail when documents in different files share an identifier, instead of matching them per file."`
+	Ignore     []string          `help:"Ignore changes at a path glob, optionally scoped to a kind (e.g. status, metadata.annotations.**, Deployment:spec.replicas). Can be repeated." sep:"none"`
+	ShowCounts bool              `short:"c" help:"Show summary counts only."`
+	Verbose    bool              `short:"v" help:"Show verbose output with full document content."`
+	NoColor    bool              `help:"Disable color output."`
+	Output     string            `short:"o" help:"Output format (text, json)." enum:"text,json" default:"text"`
+	Format     string            `help:"How modified documents are shown in text output (fields, unified)." enum:"fields,unified" default:"fields"`
+	Context    int               `short:"U" help:"Number of context lines with --format unified." default:"3"`
+
+	// Exit codes
+	ExitCode          bool `help:"Exit with 0 when there are no changes, 2 when there are changes and 1 on errors."`
+	DeletionsExitCode int  `help:"With --exit-code, exit with this code (2-255) instead of 2 when documents are deleted (0 to disable)." default:"0"`
 
 	// Git integration
 	GitCompare string `help:"Compare with Git branch (e.g., main, develop). Auto-detects changed YAML files."`
+	Base       string `help:"Compare from this Git ref, reading files from Git objects. Accepts A...B (from the merge base of A and B to B) and A..B. Auto-detects changed YAML files."`
+	Head       string `help:"Compare to this Git ref (with --base; defaults to the working tree)."`
+
+	// File selection in Git and directory mode
+	Include []string `help:"Only compare files matching these gitignore-style patterns in Git and directory mode (\"!\" negates a pattern). Can be repeated." sep:"none"`
+	Exclude []string `help:"Skip files matching these gitignore-style patterns in Git and directory mode (\"!\" negates a pattern). Can be repeated." sep:"none"`
 
 	// GitHub integration (legacy flags)
-	GithubLabel    bool   `help:"Add GitHub label based on diff results."`
-	GithubRepo     string `help:"GitHub repository (owner/repo). Required with --github-label."`
-	GithubPR       int    `help:"GitHub PR number. Required with --github-label."`
-	GithubToken    string `help:"GitHub token (or use GITHUB_TOKEN env var)."`
-	ChangesLabel   string `help:"Label to add when changes are found." default:"config-sync/changes"`
-	NoChangesLabel string `help:"Label to add when no changes are found." default:"config-sync/no-changes"`
+	GithubLabel   bool   `help:"Add GitHub label based on diff results."`
+	GithubRepo    string `help:"GitHub repository (owner/repo). Detected from the CI environment if omitted."`
+	GithubPR      int    `help:"GitHub PR number. Detected from the CI environment if omitted."`
+	GithubToken   string `help:"GitHub token (or use GITHUB_TOKEN env var)."`
+	GithubBaseURL string `name:"github-base-url" help:"GitHub Enterprise Server API base URL (e.g. https://ghe.example.com/api/v3/)."`
+	GithubUpload  string `name:"github-upload-url" help:"GitHub Enterprise Server upload URL (defaults to the base URL)."`
+	GithubCACert  string `name:"github-ca-cert" help:"PEM file with additional CA certificates for the GitHub API." type:"existingfile"`
+
+	// GitHub API request handling
+	GithubTimeout    time.Duration `help:"Timeout of each GitHub API request attempt (default 30s)."`
+	GithubMaxRetries int           `help:"Retries of GitHub API requests that failed with a transient error (default 3, -1 disables retries)."`
+
+	// GitLab (selected with --provider=gitlab, provider: gitlab in the config or in GitLab CI)
+	Provider    string `help:"Code review provider (github, gitlab). Overrides the config and the CI environment." enum:",github,gitlab" default:""`
+	GitlabURL   string `name:"gitlab-url" help:"GitLab instance URL (defaults to CI_SERVER_URL or https://gitlab.com)."`
+	GitlabToken string `help:"GitLab access token with the api scope (or use GITLAB_TOKEN env var)."`
+
+	// GitHub App authentication (instead of a token)
+	GithubAppID             int64  `name:"github-app-id" help:"GitHub App ID to authenticate as an app installation."`
+	GithubAppInstallationID int64  `name:"github-app-installation-id" help:"GitHub App installation ID."`
+	GithubAppPrivateKey     string `name:"github-app-private-key" help:"Path to the GitHub App private key (PEM)." type:"existingfile"`
+	ChangesLabel            string `help:"Label to add when changes are found." default:"config-sync/changes"`
+	NoChangesLabel          string `help:"Label to add when no changes are found." default:"config-sync/no-changes"`
 
 	// Config file (tfcmt-style)
 	Config      string            `help:"Path to yamlcmt.yaml config file." type:"existingfile"`
 	PostComment bool              `help:"Post comment to GitHub PR (requires --config)."`
+	CommentMode string            `help:"How comments from previous runs are handled (create, update, minimize, delete). Overrides the config." enum:",create,update,minimize,delete" default:""`
+	Target      string            `help:"Target name embedded in the comment marker to tell runs on the same PR apart. Overrides the config."`
+	CheckRun    bool              `help:"Publish the result as a GitHub check run with annotations on changed lines (requires --config)."`
+	SHA         string            `name:"sha" help:"Commit SHA for the check run. Detected from the CI environment if omitted."`
+	Review      bool              `help:"Post inline PR review comments on changed fields matching the review rules in the config (requires --config)."`
+	StepSummary bool              `help:"Append the rendered template to the GitHub Actions job summary ($GITHUB_STEP_SUMMARY, requires --config)."`
+	DryRun      bool              `help:"Print the comments, labels, check run and review that would be published to stderr instead of calling the GitHub or GitLab API (no token needed)."`
 	Link        string            `help:"CI build link to include in comment."`
 	Var         map[string]string `help:"Variables to pass to template (key=value)."`
 }
@@ -68,98 +117,192 @@ func main() {
 	)
 
 	err := ctx.Run(&cli)
+
+	var exitErr exitCodeError
+	if errors.As(err, &exitErr) {
+		os.Exit(int(exitErr))
+	}
 	ctx.FatalIfErrorf(err)
 }
 
-func (c *CompareCmd) Run(cli *CLI) error {
-	// Disable color if requested
-	if c.NoColor {
-		color.NoColor = true
-	}
+// exitCodeError makes the process exit with the given code without reporting an error
+type exitCodeError int
 
+func (e exitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func (c *CompareCmd) Run(cli *CLI) error {
 	var cleanup func()
 	var docs1, docs2 []parser.Document
 	var err error
 
+	if c.Context < 0 {
+		return fmt.Errorf("--context must not be negative (got %d)", c.Context)
+	}
+	if c.DeletionsExitCode != 0 {
+		if !c.ExitCode {
+			return fmt.Errorf("--deletions-exit-code requires --exit-code")
+		}
+		// 1 is the exit code of errors
+		if c.DeletionsExitCode == 1 || c.DeletionsExitCode < 0 || c.DeletionsExitCode > 255 {
+			return fmt.Errorf("--deletions-exit-code must be 0 or between 2 and 255 (got %d)", c.DeletionsExitCode)
+		}
+	}
+
+	// Load config file (used for ignore rules and GitHub integration)
+	var cfg *config.Config
+	if c.Config != "" {
+		cfg, err = config.LoadConfig(c.Config)
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+		if err := github.ValidateOversizeStrategy(cfg.YAMLCmt.Compare.Oversize.Strategy); err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+	}
+
+	base, head, err := c.gitRange()
+	if err != nil {
+		return err
+	}
+
+	filter, err := c.pathFilter(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Git comparison mode
-	if c.GitCompare != "" {
-		var targetFiles []string
+	var renames map[string]string
+	if base != "" {
+		if head != "" {
+			fmt.Fprintf(os.Stderr, "Comparing %s..%s\n", base, head)
+		}
 
 		if c.File1 == "" {
-			// No file specified → auto-detect all changed YAML files
-			targetFiles, err = git.GetChangedYAMLFiles(c.GitCompare)
+			// No file specified → auto-detect all changed YAML files, including
+			// deleted and renamed ones
+			changedFiles, err := git.GetChangedFiles(base, head)
 			if err != nil {
 				return fmt.Errorf("error getting changed files: %w", err)
 			}
-			fmt.Fprintf(os.Stderr, "Found %d changed YAML file(s)\n", len(targetFiles))
-		} else {
-			// File specified → use that file
-			targetFiles = []string{c.File1}
-		}
+			changedFiles = git.FilterChangedFiles(changedFiles, filter.Match)
+			fmt.Fprintf(os.Stderr, "Found %d changed YAML file(s)\n", len(changedFiles))
 
-		// Parse files with source tracking to handle duplicate names
-		docs1, docs2, err = git.ParseFilesWithSourceTracking(c.GitCompare, targetFiles)
-		if err != nil {
-			return fmt.Errorf("error parsing files: %w", err)
+			// Parse files with source tracking to handle duplicate names
+			docs1, docs2, err = git.ParseChangedFiles(base, head, changedFiles)
+			if err != nil {
+				return fmt.Errorf("error parsing files: %w", err)
+			}
+			renames = git.Renames(changedFiles)
+		} else {
+			// File specified → use that file, by its path in the repository
+			file, err := git.RepoPath(c.File1)
+			if err != nil {
+				return fmt.Errorf("error resolving %s: %w", c.File1, err)
+			}
+			docs1, docs2, err = git.ParseFilesBetween(base, head, []string{file})
+			if err != nil {
+				return fmt.Errorf("error parsing files: %w", err)
+			}
 		}
 		cleanup = func() {} // no cleanup needed for this approach
 
 	} else {
 		// Normal mode: require both files
 		if c.File1 == "" || c.File2 == "" {
-			return fmt.Errorf("two files are required (or use --git-compare)")
+			return fmt.Errorf("two files are required (or use --git-compare or --base)")
 		}
 		cleanup = func() {} // no cleanup needed
 
-		// Parse both files
-		docs1, err = parser.ParseMultiDocYAML(c.File1)
+		dirMode, err := isDirPair(c.File1, c.File2)
 		if err != nil {
-			return fmt.Errorf("error parsing %s: %w", c.File1, err)
+			return err
 		}
 
-		docs2, err = parser.ParseMultiDocYAML(c.File2)
-		if err != nil {
-			return fmt.Errorf("error parsing %s: %w", c.File2, err)
+		if dirMode {
+			// Directory mode: pair files by their path relative to each directory
+			docs1, err = parser.ParseDir(c.File1, filter.Match)
+			if err != nil {
+				return fmt.Errorf("error parsing %s: %w", c.File1, err)
+			}
+			docs2, err = parser.ParseDir(c.File2, filter.Match)
+			if err != nil {
+				return fmt.Errorf("error parsing %s: %w", c.File2, err)
+			}
+		} else {
+			// Parse both files
+			docs1, err = parser.ParseMultiDocYAML(c.File1)
+			if err != nil {
+				return fmt.Errorf("error parsing %s: %w", c.File1, err)
+			}
+
+			docs2, err = parser.ParseMultiDocYAML(c.File2)
+			if err != nil {
+				return fmt.Errorf("error parsing %s: %w", c.File2, err)
+			}
 		}
 	}
 	defer cleanup()
 
 	// Create diff engine
 	engine := diff.NewEngine(c.Key)
+	engine.SetListKeys(c.ListKey)
+	engine.SetFileRenames(renames)
+	engine.SetStrictIdentity(c.StrictKeys)
+
+	ignoreRules, err := c.ignoreRules(cfg)
+	if err != nil {
+		return err
+	}
+	if err := engine.SetIgnoreRules(ignoreRules); err != nil {
+		return fmt.Errorf("error configuring ignore rules: %w", err)
+	}
 
 	// Compare documents
-	result := engine.Compare(docs1, docs2)
+	result, err := engine.Compare(docs1, docs2)
+	if err != nil {
+		var dup *diff.DuplicateIdentityError
+		if errors.As(err, &dup) {
+			side, file := "new", c.File2
+			if dup.Old {
+				side, file = "old", c.File1
+			}
+			if dup.SourceFile == "" {
+				// In file mode, documents do not track their source file
+				dup.SourceFile = file
+			}
+			return fmt.Errorf("error comparing documents: %s documents: %w (use --key k8s to identify documents by apiVersion, kind, namespace and name)", side, dup)
+		}
+		return fmt.Errorf("error comparing documents: %w", err)
+	}
 
-	// Capture detailed output for comment/template
+	// Render detailed output for comment/template (never colored)
 	var detailsBuf bytes.Buffer
 	if c.Verbose {
-		// Use verbose output for details
-		oldStdout := os.Stdout
-		r, w, _ := os.Pipe()
-		os.Stdout = w
-
-		result.Print(true)
-
-		w.Close()
-		os.Stdout = oldStdout
-
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(r)
-		detailsBuf = *buf
+		c.newRenderer(&detailsBuf, false).Render(result, true)
 	}
 
 	// Print results to stdout (unless only posting comment)
 	if !c.PostComment || c.Config == "" {
-		if c.ShowCounts {
-			result.PrintSummary()
+		if c.Output == "json" {
+			if err := result.WriteJSON(os.Stdout); err != nil {
+				return fmt.Errorf("error writing JSON output: %w", err)
+			}
 		} else {
-			result.Print(c.Verbose)
+			renderer := c.newRenderer(os.Stdout, !c.NoColor && !color.NoColor)
+			if c.ShowCounts {
+				renderer.RenderSummary(result)
+			} else {
+				renderer.Render(result, c.Verbose)
+			}
 		}
 	}
 
-	// Handle config file-based GitHub integration
-	if c.Config != "" {
-		if err := c.handleConfigBasedIntegration(result, detailsBuf.String()); err != nil {
+	// Handle config file-based GitHub integration, only when publishing was asked for.
+	// Otherwise the config only provides engine options such as ignore rules.
+	if cfg != nil && c.publishRequested(cfg) {
+		if err := c.handleConfigBasedIntegration(cfg, result, detailsBuf.String()); err != nil {
 			return err
 		}
 	} else if c.GithubLabel {
@@ -169,93 +312,575 @@ func (c *CompareCmd) Run(cli *CLI) error {
 		}
 	}
 
-	return nil
+	return c.exitCode(result)
 }
 
-func (c *CompareCmd) handleConfigBasedIntegration(result *diff.Result, details string) error {
-	// Load config file
-	cfg, err := config.LoadConfig(c.Config)
+// gitRange returns the refs compared in Git mode, or an empty base in file mode.
+// An empty head means the working tree. Without files or refs, the base branch of
+// the PR detected from the CI environment is used like --git-compare.
+func (c *CompareCmd) gitRange() (string, string, error) {
+	if c.Base == "" {
+		if c.Head != "" {
+			return "", "", fmt.Errorf("--head requires --base")
+		}
+		if c.GitCompare == "" && c.File1 == "" {
+			ref, err := detectBaseRef()
+			if err != nil {
+				return "", "", err
+			}
+			c.GitCompare = ref
+		}
+		return c.GitCompare, "", nil
+	}
+	if c.GitCompare != "" {
+		return "", "", fmt.Errorf("--git-compare cannot be combined with --base")
+	}
+
+	base, head, err := git.ResolveRange(c.Base, c.Head)
 	if err != nil {
-		return fmt.Errorf("error loading config: %w", err)
+		return "", "", fmt.Errorf("error resolving git refs: %w", err)
 	}
+	return base, head, nil
+}
 
-	// Determine repo and PR number
-	repo := cfg.GetRepoFullName()
-	if c.GithubRepo != "" {
-		repo = c.GithubRepo
+// detectBaseRef returns the base branch of the PR detected from the CI environment,
+// preferring its remote-tracking branch, or an empty string if none is detected
+func detectBaseRef() (string, error) {
+	detected := ci.Detect()
+	if detected.BaseRef == "" {
+		return "", nil
 	}
-	prNumber := c.GithubPR
 
-	if repo == "" {
-		return fmt.Errorf("repository not specified in config or --github-repo")
+	for _, ref := range []string{"origin/" + detected.BaseRef, detected.BaseRef} {
+		if git.BranchExists(ref) {
+			fmt.Fprintf(os.Stderr, "Detected base branch %s from %s\n", ref, detected.Source)
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("detected base branch %s from %s, but it is not available in the local repository (fetch it or use --git-compare)", detected.BaseRef, detected.Source)
+}
+
+// exitCode returns an exitCodeError describing the diff result when --exit-code is set
+func (c *CompareCmd) exitCode(result *diff.Result) error {
+	if !c.ExitCode {
+		return nil
+	}
+
+	switch {
+	case c.DeletionsExitCode != 0 && len(result.Deleted) > 0:
+		return exitCodeError(c.DeletionsExitCode)
+	case result.HasDifferences():
+		return exitCodeError(2)
+	default:
+		return nil
+	}
+}
+
+// newRenderer creates a text renderer honoring the --format option
+func (c *CompareCmd) newRenderer(w io.Writer, useColor bool) *diff.Renderer {
+	renderer := diff.NewRenderer(w, useColor)
+	if c.Format == "unified" {
+		renderer.UseUnifiedFormat(c.Context)
 	}
-	if prNumber == 0 {
-		return fmt.Errorf("PR number not specified (use --github-pr)")
+	return renderer
+}
+
+// ignoreRules combines the ignore rules from the config file and --ignore flags
+func (c *CompareCmd) ignoreRules(cfg *config.Config) ([]diff.IgnoreRule, error) {
+	var rules []diff.IgnoreRule
+	if cfg != nil {
+		for _, ignore := range cfg.YAMLCmt.Compare.Ignore {
+			if ignore.Path == "" {
+				return nil, fmt.Errorf("ignore rule in config has no path")
+			}
+			rules = append(rules, diff.IgnoreRule{Path: ignore.Path, Kinds: ignore.Kinds})
+		}
 	}
 
-	// Validate token
-	token := c.GithubToken
-	if token == "" {
-		token = os.Getenv("GITHUB_TOKEN")
+	for _, s := range c.Ignore {
+		rule, err := diff.ParseIgnoreRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
 	}
-	if token == "" {
-		return fmt.Errorf("GitHub token not provided (use --github-token or GITHUB_TOKEN env var)")
+
+	return rules, nil
+}
+
+// pathFilter combines the path patterns from the config file and --include/--exclude flags.
+// Flags come last, so they take precedence over the config.
+func (c *CompareCmd) pathFilter(cfg *config.Config) (*pathfilter.Filter, error) {
+	var include, exclude []string
+	if cfg != nil {
+		include = append(include, cfg.YAMLCmt.Compare.Paths.Include...)
+		exclude = append(exclude, cfg.YAMLCmt.Compare.Paths.Exclude...)
+	}
+	include = append(include, c.Include...)
+	exclude = append(exclude, c.Exclude...)
+
+	filter, err := pathfilter.New(include, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring paths: %w", err)
 	}
+	return filter, nil
+}
 
+// isDirPair reports whether both paths are directories.
+// An error is returned if a path does not exist or only one is a directory.
+func isDirPair(path1, path2 string) (bool, error) {
+	info1, err := os.Stat(path1)
+	if err != nil {
+		return false, err
+	}
+	info2, err := os.Stat(path2)
+	if err != nil {
+		return false, err
+	}
+	if info1.IsDir() != info2.IsDir() {
+		return false, fmt.Errorf("cannot compare a file with a directory (%s, %s)", path1, path2)
+	}
+	return info1.IsDir(), nil
+}
+
+func (c *CompareCmd) handleConfigBasedIntegration(cfg *config.Config, result *diff.Result, details string) error {
 	compareConfig := cfg.YAMLCmt.Compare
 
-	// Post comment if requested and template is configured
-	if c.PostComment && !compareConfig.DisableComment && compareConfig.Template != "" {
-		// Prepare template variables
-		vars := make(map[string]interface{})
-		for k, v := range c.Var {
-			vars[k] = v
+	// Prepare template variables
+	vars := make(map[string]interface{})
+	for k, v := range c.Var {
+		vars[k] = v
+	}
+
+	// Prepare template data
+	templateData := github.PrepareTemplateData(result, details, c.Link, vars)
+
+	// Append to the GitHub Actions job summary (works without a PR, e.g. on push)
+	if c.StepSummary {
+		tmpl := compareConfig.StepSummaryTemplate
+		if tmpl == "" {
+			tmpl = compareConfig.Template
 		}
+		if tmpl == "" {
+			return fmt.Errorf("--step-summary requires step_summary_template or template in the config")
+		}
+
+		summary, err := github.RenderTemplate(tmpl, templateData)
+		if err != nil {
+			return fmt.Errorf("error rendering step summary template: %w", err)
+		}
+		if c.DryRun {
+			fmt.Fprintf(os.Stderr, "[dry-run] Would append to the job summary:\n%s\n", summary)
+		} else if err := github.AppendStepSummary(summary); err != nil {
+			return fmt.Errorf("error writing step summary: %w", err)
+		}
+	}
 
-		// Prepare template data
-		templateData := github.PrepareTemplateData(result, details, c.Link, vars)
+	// Determine repo and PR number
+	pr := c.pullRequestInfo(cfg.GetRepoFullName())
+	repo, prNumber := pr.Repo, pr.PRNumber
 
-		// Render template
-		commentBody, err := github.RenderTemplate(compareConfig.Template, templateData)
+	if c.DryRun && repo == "" {
+		repo = "<owner>/<repo>"
+	}
+
+	if repo == "" {
+		if c.StepSummary && !c.CheckRun && prNumber == 0 {
+			fmt.Fprintf(os.Stderr, "No PR detected, skipping PR comment and labels\n")
+			return nil
+		}
+		return fmt.Errorf("repository not specified in config or --github-repo, and not detected from the CI environment")
+	}
+
+	// Without a PR only a check run can be published, so no client is needed otherwise
+	if prNumber == 0 && !c.CheckRun && !c.DryRun {
+		if c.StepSummary {
+			fmt.Fprintf(os.Stderr, "No PR detected, skipping PR comment and labels\n")
+			return nil
+		}
+		return fmt.Errorf("PR number not specified (use --github-pr), and not detected from the CI environment")
+	}
+
+	client, err := c.newClient(cfg, pr)
+	if err != nil {
+		return err
+	}
+
+	// Publish a check run on the head commit (works without a PR, e.g. on push)
+	if c.CheckRun {
+		publisher, err := githubOnly(client, "--check-run")
+		if err != nil {
+			return err
+		}
+		if err := c.publishCheckRun(publisher, repo, pr.HeadSHA, compareConfig.CheckRun, result, templateData); err != nil {
+			return err
+		}
+	}
+
+	if prNumber == 0 && !c.DryRun {
+		if c.CheckRun {
+			fmt.Fprintf(os.Stderr, "No PR detected, skipping PR comment and labels\n")
+			return nil
+		}
+		return fmt.Errorf("PR number not specified (use --github-pr), and not detected from the CI environment")
+	}
+
+	// Post comment if requested and template is configured
+	if c.PostComment && !compareConfig.DisableComment && compareConfig.Template != "" {
+		// Render template, degrading bodies that exceed GitHub's size limit
+		oversize := compareConfig.Oversize
+		commentBodies, err := github.RenderComments(compareConfig.Template, templateData, oversize.Strategy, oversize.Template)
 		if err != nil {
 			return fmt.Errorf("error rendering template: %w", err)
 		}
 
-		// Post comment
-		if err := github.PostComment(repo, prNumber, commentBody); err != nil {
+		// Post comment, replacing comments from previous runs according to the mode
+		mode := compareConfig.Comment.Mode
+		if c.CommentMode != "" {
+			mode = c.CommentMode
+		}
+		target := compareConfig.Comment.Target
+		if c.Target != "" {
+			target = c.Target
+		}
+		if err := client.UpsertComment(repo, prNumber, commentBodies, mode, target); err != nil {
 			return fmt.Errorf("error posting comment: %w", err)
 		}
 	}
 
-	// Add label if not disabled
+	// Post inline review comments on the changed lines of risky fields
+	if c.Review {
+		publisher, err := githubOnly(client, "--review")
+		if err != nil {
+			return err
+		}
+		if err := c.postReview(publisher, repo, prNumber, compareConfig.Review, result, templateData); err != nil {
+			return err
+		}
+	}
+
+	// Reconcile labels if not disabled: add applicable ones and remove stale ones
 	if !compareConfig.DisableLabel {
-		labels := compareConfig.GetLabels(len(result.Added), len(result.Deleted), len(result.Modified))
-		if len(labels) > 0 {
-			if err := github.AddLabels(repo, prNumber, labels); err != nil {
-				return fmt.Errorf("error adding labels: %w", err)
-			}
+		labels := compareConfig.GetLabels(len(result.Added), len(result.Deleted), len(result.Modified), len(result.Moved))
+		if err := client.ReconcileLabels(repo, prNumber, labels, compareConfig.ManagedLabels()); err != nil {
+			return fmt.Errorf("error updating labels: %w", err)
 		}
 	}
 
 	return nil
 }
 
+// publishRequested reports whether the config-based integration should run:
+// a publish flag is set, a repository or PR is given, or a PR is detected from CI
+func (c *CompareCmd) publishRequested(cfg *config.Config) bool {
+	if c.PostComment || c.CheckRun || c.Review || c.StepSummary || c.DryRun {
+		return true
+	}
+	if cfg.GetRepoFullName() != "" || c.GithubRepo != "" || c.GithubPR != 0 {
+		return true
+	}
+	return ci.Detect().PRNumber != 0
+}
+
+// publishCheckRun creates a check run whose conclusion follows the check_run rules in the config
+func (c *CompareCmd) publishCheckRun(client github.Publisher, repo string, detectedSHA string, checkConfig config.CheckRunConfig, result *diff.Result, templateData github.TemplateData) error {
+	sha := c.SHA
+	if sha == "" {
+		sha = detectedSHA
+	}
+	if sha == "" && c.DryRun {
+		sha = "<sha>"
+	}
+	if sha == "" {
+		return fmt.Errorf("commit SHA for the check run not specified (use --sha), and not detected from the CI environment")
+	}
+
+	conclusion, err := checkConfig.Conclusion(len(result.Added), len(result.Deleted), len(result.Modified), len(result.Moved))
+	if err != nil {
+		return err
+	}
+	// GitHub links the "action required" button to the details URL, so it must be set
+	if conclusion == "action_required" && c.Link == "" {
+		return fmt.Errorf("check run conclusion action_required requires --link for the details URL")
+	}
+
+	tmpl := checkConfig.Template
+	if tmpl == "" {
+		tmpl = defaultCheckRunTemplate
+	}
+	summary, err := github.RenderTemplate(tmpl, templateData)
+	if err != nil {
+		return fmt.Errorf("error rendering check run template: %w", err)
+	}
+
+	paths, err := c.repoPaths()
+	if err != nil {
+		return err
+	}
+
+	err = client.CreateCheckRun(repo, github.CheckRunOptions{
+		Name:        checkConfig.GetName(),
+		HeadSHA:     sha,
+		Conclusion:  conclusion,
+		Title:       templateData.Summary,
+		Summary:     summary,
+		DetailsURL:  c.Link,
+		Annotations: github.BuildAnnotations(result, paths),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating check run: %w", err)
+	}
+	return nil
+}
+
+// postReview posts one review with a comment on each change matching the review rules
+func (c *CompareCmd) postReview(client github.Publisher, repo string, prNumber int, reviewConfig config.ReviewConfig, result *diff.Result, templateData github.TemplateData) error {
+	if len(reviewConfig.Rules) == 0 {
+		return fmt.Errorf("--review requires review.rules in the config")
+	}
+
+	rules := make([]github.ReviewRule, 0, len(reviewConfig.Rules))
+	for _, rule := range reviewConfig.Rules {
+		rules = append(rules, github.ReviewRule{Path: rule.Path, Kinds: rule.Kinds, Message: rule.Message})
+	}
+
+	paths, err := c.repoPaths()
+	if err != nil {
+		return err
+	}
+
+	comments, err := github.BuildReviewComments(result, rules, paths)
+	if err != nil {
+		return err
+	}
+	if len(comments) == 0 {
+		fmt.Fprintf(os.Stderr, "No changes match the review rules, skipping review\n")
+		return nil
+	}
+
+	if err := client.CreateReview(repo, prNumber, templateData.Summary, comments); err != nil {
+		return fmt.Errorf("error posting review: %w", err)
+	}
+	return nil
+}
+
+// repoPaths returns the mapper from source files to the repository-relative paths used
+// by annotations and review comments. In file mode, changes are placed on the second
+// file; in directory mode, source files are relative to the second directory. Old
+// documents only have a path in Git mode, where they are read from the base version.
+func (c *CompareCmd) repoPaths() (github.PathMapper, error) {
+	root, err := git.TopLevel()
+	if err != nil {
+		if !c.DryRun {
+			return nil, fmt.Errorf("error resolving paths for GitHub: %w", err)
+		}
+		root, _ = os.Getwd()
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	gitMode := c.GitCompare != "" || c.Base != ""
+	dirMode := false
+	if !gitMode {
+		if info, err := os.Stat(c.File2); err == nil {
+			dirMode = info.IsDir()
+		}
+	}
+
+	return func(sourceFile string, old bool) string {
+		if old && !gitMode {
+			// Both versions are read from the working tree, so line numbers of old
+			// documents do not refer to the base of the pull request
+			return ""
+		}
+
+		path := sourceFile
+		switch {
+		case dirMode:
+			path = filepath.Join(c.File2, sourceFile)
+		case !gitMode:
+			path = c.File2
+		case !filepath.IsAbs(path):
+			// Paths detected by Git are already relative to the repository root
+			return filepath.ToSlash(path)
+		}
+
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return ""
+		}
+		return filepath.ToSlash(rel)
+	}, nil
+}
+
+// defaultCheckRunTemplate is used for the check run summary when check_run.template is not set
+const defaultCheckRunTemplate = `{{.Summary}}
+{{if .Details}}
+` + "```" + `
+{{.Details}}
+` + "```" + `
+{{end}}`
+
+// newClient creates the client of the selected code review provider.
+// With --dry-run, a client printing the intended actions is returned instead.
+// The dry run is printed to stderr, so that it does not mix with -o json on stdout.
+func (c *CompareCmd) newClient(cfg *config.Config, pr ci.Info) (vcs.Provider, error) {
+	provider, err := c.providerName(cfg, pr.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.DryRun {
+		if provider == vcs.GitLab {
+			return vcs.NewDryRun(os.Stderr, vcs.GitLab), nil
+		}
+		return github.NewDryRun(os.Stderr), nil
+	}
+	if provider == vcs.GitLab {
+		return c.newGitlabClient(cfg, pr.ServerURL)
+	}
+	return c.newGithubClient(cfg)
+}
+
+// providerName returns the provider from the flag, the config or the CI environment,
+// in that order of precedence, defaulting to GitHub
+func (c *CompareCmd) providerName(cfg *config.Config, detected string) (string, error) {
+	provider := c.Provider
+	if provider == "" && cfg != nil {
+		provider = cfg.Provider
+	}
+	if provider == "" {
+		provider = detected
+	}
+
+	switch provider {
+	case "", vcs.GitHub:
+		return vcs.GitHub, nil
+	case vcs.GitLab:
+		return vcs.GitLab, nil
+	}
+	return "", fmt.Errorf("unknown provider: %s (expected: github or gitlab)", provider)
+}
+
+// githubOnly returns the client for a feature that only GitHub supports
+func githubOnly(client vcs.Provider, feature string) (github.Publisher, error) {
+	publisher, ok := client.(github.Publisher)
+	if !ok {
+		return nil, fmt.Errorf("%s is only supported with GitHub", feature)
+	}
+	return publisher, nil
+}
+
+// newGitlabClient creates a GitLab client from flags, the GITLAB_TOKEN environment
+// variable, the config file and the CI environment, in that order of precedence.
+func (c *CompareCmd) newGitlabClient(cfg *config.Config, detectedURL string) (*gitlab.Client, error) {
+	clientConfig := gitlab.ClientConfig{
+		Token:   c.GitlabToken,
+		BaseURL: c.GitlabURL,
+	}
+	if clientConfig.Token == "" {
+		clientConfig.Token = os.Getenv("GITLAB_TOKEN")
+	}
+	if cfg != nil {
+		if clientConfig.BaseURL == "" {
+			clientConfig.BaseURL = cfg.GitLab.BaseURL
+		}
+		clientConfig.CACertFile = cfg.GitLab.CACertFile
+		clientConfig.Timeout = cfg.GitLab.Timeout
+	}
+	if clientConfig.BaseURL == "" {
+		clientConfig.BaseURL = detectedURL
+	}
+
+	if clientConfig.Token == "" {
+		return nil, fmt.Errorf("GitLab token not provided (use --gitlab-token or GITLAB_TOKEN env var)")
+	}
+
+	client, err := gitlab.NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GitLab client: %w", err)
+	}
+	return client, nil
+}
+
+// newGithubClient creates a GitHub client from flags, the GITHUB_TOKEN environment
+// variable and the config file, in that order of precedence.
+// GitHub App credentials are used instead of the token when an app ID is set.
+func (c *CompareCmd) newGithubClient(cfg *config.Config) (*github.Client, error) {
+	clientConfig := github.ClientConfig{
+		Token:      c.GithubToken,
+		BaseURL:    c.GithubBaseURL,
+		UploadURL:  c.GithubUpload,
+		CACertFile: c.GithubCACert,
+		Timeout:    c.GithubTimeout,
+		MaxRetries: c.GithubMaxRetries,
+	}
+	if clientConfig.Token == "" {
+		clientConfig.Token = os.Getenv("GITHUB_TOKEN")
+	}
+	if cfg != nil {
+		if clientConfig.BaseURL == "" {
+			clientConfig.BaseURL = cfg.GitHub.BaseURL
+		}
+		if clientConfig.UploadURL == "" {
+			clientConfig.UploadURL = cfg.GitHub.UploadURL
+		}
+		if clientConfig.CACertFile == "" {
+			clientConfig.CACertFile = cfg.GitHub.CACertFile
+		}
+		if clientConfig.Timeout == 0 {
+			clientConfig.Timeout = cfg.GitHub.Timeout
+		}
+		if clientConfig.MaxRetries == 0 {
+			clientConfig.MaxRetries = cfg.GitHub.MaxRetries
+		}
+	}
+
+	// GitHub App credentials take precedence over the token
+	app := github.AppConfig{
+		AppID:          c.GithubAppID,
+		InstallationID: c.GithubAppInstallationID,
+		PrivateKeyFile: c.GithubAppPrivateKey,
+	}
+	if app.AppID == 0 && cfg != nil {
+		app = github.AppConfig{
+			AppID:          cfg.GitHub.App.AppID,
+			InstallationID: cfg.GitHub.App.InstallationID,
+			PrivateKeyFile: cfg.GitHub.App.PrivateKeyFile,
+		}
+	}
+	if app.AppID != 0 {
+		clientConfig.App = &app
+	}
+
+	if clientConfig.Token == "" && clientConfig.App == nil {
+		return nil, fmt.Errorf("GitHub token not provided (use --github-token, GITHUB_TOKEN env var or GitHub App credentials)")
+	}
+
+	client, err := github.NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GitHub client: %w", err)
+	}
+	return client, nil
+}
+
 func (c *CompareCmd) applyGithubLabel(result *diff.Result) error {
 	// Validate required parameters
-	if c.GithubRepo == "" {
-		return fmt.Errorf("--github-repo is required when using --github-label")
+	pr := c.pullRequestInfo("")
+	if pr.Repo == "" {
+		return fmt.Errorf("--github-repo is required when using --github-label outside a supported CI environment")
 	}
-	if c.GithubPR == 0 {
-		return fmt.Errorf("--github-pr is required when using --github-label")
+	if pr.PRNumber == 0 {
+		return fmt.Errorf("--github-pr is required when using --github-label outside a supported CI environment")
 	}
 
-	// Get token from flag or environment
-	token := c.GithubToken
-	if token == "" {
-		token = os.Getenv("GITHUB_TOKEN")
-	}
-	if token == "" {
-		return fmt.Errorf("GitHub token not provided (use --github-token or GITHUB_TOKEN env var)")
+	client, err := c.newClient(nil, pr)
+	if err != nil {
+		return err
 	}
 
 	// Determine which label to apply
@@ -264,6 +889,38 @@ func (c *CompareCmd) applyGithubLabel(result *diff.Result) error {
 		label = c.ChangesLabel
 	}
 
-	// Apply label using go-github
-	return github.AddLabel(c.GithubRepo, c.GithubPR, label)
+	// Apply label using go-github, removing the other one if it is left from a previous run
+	return client.ReconcileLabels(pr.Repo, pr.PRNumber, []string{label}, []string{c.ChangesLabel, c.NoChangesLabel})
+}
+
+// pullRequestInfo determines the repository and PR from flags and the config file,
+// filling in anything missing from the CI environment
+func (c *CompareCmd) pullRequestInfo(configRepo string) ci.Info {
+	info := ci.Info{
+		Repo:     configRepo,
+		PRNumber: c.GithubPR,
+	}
+	if c.GithubRepo != "" {
+		info.Repo = c.GithubRepo
+	}
+
+	detected := ci.Detect()
+	if detected.Source == "" {
+		return info
+	}
+
+	if info.Repo == "" && detected.Repo != "" {
+		info.Repo = detected.Repo
+		fmt.Fprintf(os.Stderr, "Detected repository %s from %s\n", info.Repo, detected.Source)
+	}
+	if info.PRNumber == 0 && detected.PRNumber != 0 {
+		info.PRNumber = detected.PRNumber
+		fmt.Fprintf(os.Stderr, "Detected PR #%d from %s\n", info.PRNumber, detected.Source)
+	}
+	info.HeadSHA = detected.HeadSHA
+	info.Source = detected.Source
+	info.Provider = detected.Provider
+	info.ServerURL = detected.ServerURL
+
+	return info
 }
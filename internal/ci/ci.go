@@ -0,0 +1,197 @@
+package ci
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tyuhara/yamlcmt/internal/vcs"
+)
+
+// Info describes the repository and pull request that a CI run belongs to
+type Info struct {
+	Source   string // Name of the CI environment the values were read from
+	Provider string // vcs.GitHub or vcs.GitLab, empty if unknown
+	Repo     string // owner/repo, or the project path on GitLab
+	PRNumber int    // Pull request number, or merge request IID on GitLab
+	HeadSHA  string
+	BaseRef  string
+
+	// ServerURL is the URL of the GitLab instance
+	ServerURL string
+}
+
+// detectors are tried in order; generic variables come last as a fallback
+var detectors = []func() (Info, bool){
+	detectGitHubActions,
+	detectGitLabCI,
+	detectCircleCI,
+	detectDrone,
+	detectGeneric,
+}
+
+// Detect reads Info from well-known CI environment variables.
+// The zero Info is returned if no CI environment is recognized.
+func Detect() Info {
+	for _, detect := range detectors {
+		if info, ok := detect(); ok {
+			return info
+		}
+	}
+	return Info{}
+}
+
+// detectGitHubActions reads GITHUB_* variables and the event payload at GITHUB_EVENT_PATH
+func detectGitHubActions() (Info, bool) {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return Info{}, false
+	}
+
+	info := Info{
+		Source:   "GitHub Actions",
+		Provider: vcs.GitHub,
+		Repo:     os.Getenv("GITHUB_REPOSITORY"),
+		HeadSHA:  os.Getenv("GITHUB_SHA"),
+		BaseRef:  os.Getenv("GITHUB_BASE_REF"),
+	}
+
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			var event struct {
+				Number      int `json:"number"`
+				PullRequest *struct {
+					Number int `json:"number"`
+					Head   struct {
+						SHA string `json:"sha"`
+					} `json:"head"`
+					Base struct {
+						Ref string `json:"ref"`
+					} `json:"base"`
+				} `json:"pull_request"`
+				Issue *struct {
+					Number      int       `json:"number"`
+					PullRequest *struct{} `json:"pull_request"`
+				} `json:"issue"`
+			}
+			if err := json.Unmarshal(data, &event); err == nil {
+				switch {
+				case event.PullRequest != nil:
+					// pull_request and pull_request_target events
+					info.PRNumber = event.PullRequest.Number
+					info.HeadSHA = event.PullRequest.Head.SHA
+					info.BaseRef = event.PullRequest.Base.Ref
+				case event.Issue != nil && event.Issue.PullRequest != nil:
+					// issue_comment events on a pull request
+					info.PRNumber = event.Issue.Number
+				}
+			}
+		}
+	}
+
+	// refs/pull/<number>/merge
+	if info.PRNumber == 0 {
+		info.PRNumber = prNumberFromRef(os.Getenv("GITHUB_REF"))
+	}
+
+	return info, true
+}
+
+// detectGitLabCI reads the predefined CI_* variables of GitLab CI/CD
+func detectGitLabCI() (Info, bool) {
+	if os.Getenv("GITLAB_CI") != "true" {
+		return Info{}, false
+	}
+
+	return Info{
+		Source:    "GitLab CI",
+		Provider:  vcs.GitLab,
+		Repo:      os.Getenv("CI_PROJECT_PATH"),
+		PRNumber:  atoi(os.Getenv("CI_MERGE_REQUEST_IID")),
+		HeadSHA:   os.Getenv("CI_COMMIT_SHA"),
+		BaseRef:   os.Getenv("CI_MERGE_REQUEST_TARGET_BRANCH_NAME"),
+		ServerURL: os.Getenv("CI_SERVER_URL"),
+	}, true
+}
+
+// detectCircleCI reads CIRCLE_* variables
+func detectCircleCI() (Info, bool) {
+	if os.Getenv("CIRCLECI") != "true" {
+		return Info{}, false
+	}
+
+	info := Info{
+		Source:  "CircleCI",
+		HeadSHA: os.Getenv("CIRCLE_SHA1"),
+	}
+	if owner, name := os.Getenv("CIRCLE_PROJECT_USERNAME"), os.Getenv("CIRCLE_PROJECT_REPONAME"); owner != "" && name != "" {
+		info.Repo = owner + "/" + name
+	}
+
+	info.PRNumber = atoi(os.Getenv("CIRCLE_PR_NUMBER"))
+	if info.PRNumber == 0 {
+		// https://github.com/owner/repo/pull/123
+		info.PRNumber = prNumberFromURL(os.Getenv("CIRCLE_PULL_REQUEST"))
+	}
+
+	return info, true
+}
+
+// detectDrone reads DRONE_* variables
+func detectDrone() (Info, bool) {
+	if os.Getenv("DRONE") != "true" {
+		return Info{}, false
+	}
+
+	return Info{
+		Source:   "Drone",
+		Repo:     os.Getenv("DRONE_REPO"),
+		PRNumber: atoi(os.Getenv("DRONE_PULL_REQUEST")),
+		HeadSHA:  os.Getenv("DRONE_COMMIT_SHA"),
+		BaseRef:  os.Getenv("DRONE_TARGET_BRANCH"),
+	}, true
+}
+
+// detectGeneric reads the CI_* variables used by Woodpecker and other CI systems
+func detectGeneric() (Info, bool) {
+	repo := os.Getenv("CI_REPO")
+	if repo == "" || strings.Count(repo, "/") != 1 {
+		return Info{}, false
+	}
+
+	return Info{
+		Source:   "CI_* environment variables",
+		Repo:     repo,
+		PRNumber: atoi(os.Getenv("CI_COMMIT_PULL_REQUEST")),
+		HeadSHA:  os.Getenv("CI_COMMIT_SHA"),
+		BaseRef:  os.Getenv("CI_COMMIT_TARGET_BRANCH"),
+	}, true
+}
+
+var (
+	pullRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/`)
+	pullURLPattern = regexp.MustCompile(`/pull/(\d+)/?$`)
+)
+
+func prNumberFromRef(ref string) int {
+	if m := pullRefPattern.FindStringSubmatch(ref); m != nil {
+		return atoi(m[1])
+	}
+	return 0
+}
+
+func prNumberFromURL(url string) int {
+	if m := pullURLPattern.FindStringSubmatch(url); m != nil {
+		return atoi(m[1])
+	}
+	return 0
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -3,15 +3,49 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the yamlcmt configuration
 type Config struct {
-	RepoOwner string         `yaml:"repo_owner"`
-	RepoName  string         `yaml:"repo_name"`
-	YAMLCmt   YAMLCmtConfig  `yaml:"yamlcmt"`
+	// Provider is github (default) or gitlab
+	Provider  string        `yaml:"provider"`
+	RepoOwner string        `yaml:"repo_owner"`
+	RepoName  string        `yaml:"repo_name"`
+	GitHub    GitHubConfig  `yaml:"github"`
+	GitLab    GitLabConfig  `yaml:"gitlab"`
+	YAMLCmt   YAMLCmtConfig `yaml:"yamlcmt"`
+}
+
+// GitHubConfig represents the GitHub API connection settings
+type GitHubConfig struct {
+	// BaseURL and UploadURL are set for GitHub Enterprise Server
+	BaseURL    string    `yaml:"base_url"`
+	UploadURL  string    `yaml:"upload_url"`
+	CACertFile string    `yaml:"ca_cert_file"`
+	App        AppConfig `yaml:"app"`
+
+	// Timeout limits each attempt of an API request, e.g. "30s"
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxRetries of requests that failed with a transient error (-1 disables retries)
+	MaxRetries int `yaml:"max_retries"`
+}
+
+// GitLabConfig represents the GitLab API connection settings
+type GitLabConfig struct {
+	// BaseURL is the URL of the GitLab instance (defaults to CI_SERVER_URL or https://gitlab.com)
+	BaseURL    string        `yaml:"base_url"`
+	CACertFile string        `yaml:"ca_cert_file"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+// AppConfig represents GitHub App credentials used instead of a token
+type AppConfig struct {
+	AppID          int64  `yaml:"app_id"`
+	InstallationID int64  `yaml:"installation_id"`
+	PrivateKeyFile string `yaml:"private_key_file"`
 }
 
 // YAMLCmtConfig represents the yamlcmt-specific configuration
@@ -21,13 +55,76 @@ type YAMLCmtConfig struct {
 
 // CompareConfig represents the compare command configuration
 type CompareConfig struct {
-	Template             string      `yaml:"template"`
-	WhenHasAdditions     LabelConfig `yaml:"when_has_additions"`
-	WhenHasDeletions     LabelConfig `yaml:"when_has_deletions"`
-	WhenHasModifications LabelConfig `yaml:"when_has_modifications"`
-	WhenNoChanges        LabelConfig `yaml:"when_no_changes"`
-	DisableComment       bool        `yaml:"disable_comment"`
-	DisableLabel         bool        `yaml:"disable_label"`
+	Template             string         `yaml:"template"`
+	StepSummaryTemplate  string         `yaml:"step_summary_template"`
+	WhenHasAdditions     LabelConfig    `yaml:"when_has_additions"`
+	WhenHasDeletions     LabelConfig    `yaml:"when_has_deletions"`
+	WhenHasModifications LabelConfig    `yaml:"when_has_modifications"`
+	WhenHasMoves         LabelConfig    `yaml:"when_has_moves"`
+	WhenNoChanges        LabelConfig    `yaml:"when_no_changes"`
+	DisableComment       bool           `yaml:"disable_comment"`
+	DisableLabel         bool           `yaml:"disable_label"`
+	Ignore               []IgnoreConfig `yaml:"ignore"`
+	Paths                PathsConfig    `yaml:"paths"`
+	Comment              CommentConfig  `yaml:"comment"`
+	CheckRun             CheckRunConfig `yaml:"check_run"`
+	Review               ReviewConfig   `yaml:"review"`
+	Oversize             OversizeConfig `yaml:"oversize"`
+}
+
+// OversizeConfig represents how comments exceeding GitHub's size limit are handled
+type OversizeConfig struct {
+	// Strategy is one of truncate (default), split or summary
+	Strategy string `yaml:"strategy"`
+	// Template is rendered by the summary strategy (defaults to template without .Details)
+	Template string `yaml:"template"`
+}
+
+// ReviewConfig represents the inline PR review comments posted with --review
+type ReviewConfig struct {
+	Rules []ReviewRuleConfig `yaml:"rules"`
+}
+
+// ReviewRuleConfig selects field changes that get an inline review comment.
+// Path uses the same glob syntax as ignore rules.
+type ReviewRuleConfig struct {
+	Path    string   `yaml:"path"`
+	Kinds   []string `yaml:"kinds"`
+	Message string   `yaml:"message"`
+}
+
+// CheckRunConfig represents the GitHub check run published with --check-run.
+// Each when_* field is a check run conclusion: success, neutral, action_required or failure.
+type CheckRunConfig struct {
+	Name                 string `yaml:"name"`
+	Template             string `yaml:"template"`
+	WhenHasAdditions     string `yaml:"when_has_additions"`
+	WhenHasDeletions     string `yaml:"when_has_deletions"`
+	WhenHasModifications string `yaml:"when_has_modifications"`
+	WhenHasMoves         string `yaml:"when_has_moves"`
+	WhenNoChanges        string `yaml:"when_no_changes"`
+}
+
+// CommentConfig represents how comments from previous runs are handled
+type CommentConfig struct {
+	// Mode is one of create (default), update, minimize or delete
+	Mode string `yaml:"mode"`
+	// Target distinguishes comments of different yamlcmt runs on the same PR
+	// (e.g. "prod" embeds <!-- yamlcmt:prod -->)
+	Target string `yaml:"target"`
+}
+
+// PathsConfig selects the files compared in Git mode and directory mode with
+// gitignore-style patterns (a leading "!" negates a pattern)
+type PathsConfig struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// IgnoreConfig represents a path whose changes are ignored
+type IgnoreConfig struct {
+	Path  string   `yaml:"path"`
+	Kinds []string `yaml:"kinds"`
 }
 
 // LabelConfig represents label configuration
@@ -59,17 +156,18 @@ func (c *Config) GetRepoFullName() string {
 }
 
 // GetLabels returns all applicable labels based on diff result
-// Labels are cumulative - if there are additions, deletions, and modifications,
-// all three labels will be returned
-func (c *CompareConfig) GetLabels(added, deleted, modified int) []string {
+// Labels are cumulative - if there are additions, deletions, modifications and moves,
+// all four labels will be returned
+func (c *CompareConfig) GetLabels(added, deleted, modified, moved int) []string {
 	var labels []string
 
 	hasAdd := added > 0
 	hasDelete := deleted > 0
 	hasModify := modified > 0
+	hasMove := moved > 0
 
 	// No changes at all
-	if !hasAdd && !hasDelete && !hasModify {
+	if !hasAdd && !hasDelete && !hasModify && !hasMove {
 		if c.WhenNoChanges.Label != "" {
 			labels = append(labels, c.WhenNoChanges.Label)
 		}
@@ -91,5 +189,81 @@ func (c *CompareConfig) GetLabels(added, deleted, modified int) []string {
 		labels = append(labels, c.WhenHasModifications.Label)
 	}
 
+	// Add label for moves between files
+	if hasMove && c.WhenHasMoves.Label != "" {
+		labels = append(labels, c.WhenHasMoves.Label)
+	}
+
 	return labels
 }
+
+// ManagedLabels returns every label defined in the config.
+// These are the labels yamlcmt removes from a PR when they no longer apply.
+func (c *CompareConfig) ManagedLabels() []string {
+	var labels []string
+	for _, lc := range []LabelConfig{c.WhenHasAdditions, c.WhenHasDeletions, c.WhenHasModifications, c.WhenHasMoves, c.WhenNoChanges} {
+		if lc.Label != "" {
+			labels = append(labels, lc.Label)
+		}
+	}
+	return labels
+}
+
+// conclusionSeverity orders check run conclusions from least to most severe
+var conclusionSeverity = map[string]int{
+	"success":         0,
+	"neutral":         1,
+	"action_required": 2,
+	"failure":         3,
+}
+
+// GetName returns the check run name, defaulting to "yamlcmt"
+func (c *CheckRunConfig) GetName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return "yamlcmt"
+}
+
+// Conclusion returns the check run conclusion for a diff result.
+// When several kinds of changes exist the most severe conclusion wins.
+// Unset rules default to success without changes and neutral with changes.
+func (c *CheckRunConfig) Conclusion(added, deleted, modified, moved int) (string, error) {
+	rule := func(value, fallback string) (string, error) {
+		if value == "" {
+			return fallback, nil
+		}
+		if _, ok := conclusionSeverity[value]; !ok {
+			return "", fmt.Errorf("invalid check run conclusion: %s (expected: success, neutral, action_required or failure)", value)
+		}
+		return value, nil
+	}
+
+	if added == 0 && deleted == 0 && modified == 0 && moved == 0 {
+		return rule(c.WhenNoChanges, "success")
+	}
+
+	conclusion := "success"
+	for _, r := range []struct {
+		count int
+		value string
+	}{
+		{added, c.WhenHasAdditions},
+		{deleted, c.WhenHasDeletions},
+		{modified, c.WhenHasModifications},
+		{moved, c.WhenHasMoves},
+	} {
+		if r.count == 0 {
+			continue
+		}
+		value, err := rule(r.value, "neutral")
+		if err != nil {
+			return "", err
+		}
+		if conclusionSeverity[value] > conclusionSeverity[conclusion] {
+			conclusion = value
+		}
+	}
+
+	return conclusion, nil
+}
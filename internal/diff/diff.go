@@ -1,16 +1,27 @@
 package diff
 
 import (
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 
-	"github.com/fatih/color"
 	"github.com/tyuhara/yamlcmt/internal/parser"
 )
 
+// KeyPresets maps preset names that can be passed as an identifier path to
+// the list of paths they expand to
+var KeyPresets = map[string][]string{
+	"k8s": {"apiVersion", "kind", "metadata.namespace", "metadata.name"},
+}
+
 // Engine handles the comparison of YAML documents
 type Engine struct {
-	identifierPath string
+	identifierPaths []string
+	compareOptions  parser.CompareOptions
+	ignoreRules     []compiledIgnoreRule
+	fileRenames     map[string]string // old path → new path
+	strictIdentity  bool
 }
 
 // Result represents the result of a comparison
@@ -18,31 +29,104 @@ type Result struct {
 	Added    map[string]parser.Document
 	Deleted  map[string]parser.Document
 	Modified map[string]ModifiedDoc
+	Moved    map[string]MovedDoc
 }
 
 // ModifiedDoc represents a modified document with its changes
 type ModifiedDoc struct {
-	Old    parser.Document
-	New    parser.Document
-	Diffs  []string
+	Old     parser.Document
+	New     parser.Document
+	Changes []parser.Change
+}
+
+// MovedDoc represents a document that moved to another file, with any changes to its content
+type MovedDoc struct {
+	Old     parser.Document
+	New     parser.Document
+	Changes []parser.Change
+}
+
+// DuplicateIdentityError reports two documents in the same file that share an identity
+type DuplicateIdentityError struct {
+	Identity   string
+	SourceFile string // Empty when documents were not read from tracked files
+	First      int    // Position of the documents within the file, from 1
+	Second     int
+	Old        bool // Whether the documents are on the old side of the comparison
+}
+
+func (e *DuplicateIdentityError) Error() string {
+	in := ""
+	if e.SourceFile != "" {
+		in = " in " + e.SourceFile
+	}
+	return fmt.Sprintf("documents #%d and #%d%s have the same identity %q", e.First, e.Second, in, e.Identity)
 }
 
-// NewEngine creates a new diff engine with the specified identifier path
-func NewEngine(identifierPath string) *Engine {
+// NewEngine creates a new diff engine with the specified identifier paths.
+// The document identity is the tuple of the values found at each path, and
+// preset names from KeyPresets are expanded in place.
+func NewEngine(identifierPaths []string) *Engine {
+	var paths []string
+	for _, path := range identifierPaths {
+		if preset, ok := KeyPresets[path]; ok {
+			paths = append(paths, preset...)
+		} else {
+			paths = append(paths, path)
+		}
+	}
+
 	return &Engine{
-		identifierPath: identifierPath,
+		identifierPaths: paths,
 	}
 }
 
-// Compare compares two sets of documents
-func (e *Engine) Compare(docs1, docs2 []parser.Document) *Result {
-	map1 := e.makeDocMap(docs1)
-	map2 := e.makeDocMap(docs2)
+// SetListKeys configures the merge key used to match elements of the list at
+// each path (e.g. "spec.template.spec.containers" → "name")
+func (e *Engine) SetListKeys(listKeys map[string]string) {
+	e.compareOptions.ListKeys = listKeys
+}
+
+// SetFileRenames pairs the documents of renamed files, so that documents from
+// an old path are compared with documents from its new path (old path → new path)
+func (e *Engine) SetFileRenames(renames map[string]string) {
+	e.fileRenames = renames
+}
+
+// SetStrictIdentity makes documents in different files that share an identity an
+// error. By default, such documents are matched per file instead.
+func (e *Engine) SetStrictIdentity(strict bool) {
+	e.strictIdentity = strict
+}
+
+// Compare compares two sets of documents.
+// Documents are matched by identity regardless of their source file, so a document
+// whose file changed is reported as moved. An error is returned if two documents
+// in the same file share an identity.
+func (e *Engine) Compare(docs1, docs2 []parser.Document) (*Result, error) {
+	qualified, err := e.duplicateIdentities(docs1, docs2)
+	if err != nil {
+		return nil, err
+	}
+
+	map1, err := e.makeDocMap(docs1, e.fileRenames, qualified)
+	if err != nil {
+		var dup *DuplicateIdentityError
+		if errors.As(err, &dup) {
+			dup.Old = true
+		}
+		return nil, fmt.Errorf("old documents: %w", err)
+	}
+	map2, err := e.makeDocMap(docs2, nil, qualified)
+	if err != nil {
+		return nil, fmt.Errorf("new documents: %w", err)
+	}
 
 	result := &Result{
 		Added:    make(map[string]parser.Document),
 		Deleted:  make(map[string]parser.Document),
 		Modified: make(map[string]ModifiedDoc),
+		Moved:    make(map[string]MovedDoc),
 	}
 
 	// Find all unique keys
@@ -65,140 +149,174 @@ func (e *Engine) Compare(docs1, docs2 []parser.Document) *Result {
 		} else if exists1 && !exists2 {
 			// Deleted
 			result.Deleted[key] = doc1
+		} else if e.renamed(doc1.SourceFile) != doc2.SourceFile {
+			// Moved to another file, possibly with changes
+			var changes []parser.Change
+			if doc1.Raw != doc2.Raw {
+				changes = parser.CompareDocuments(doc1, doc2, e.compareOptionsFor(doc1, doc2))
+			}
+			result.Moved[key] = MovedDoc{
+				Old:     doc1,
+				New:     doc2,
+				Changes: changes,
+			}
 		} else if doc1.Raw != doc2.Raw {
-			// Modified
-			diffs := parser.CompareValues("", doc1.Content, doc2.Content)
-			result.Modified[key] = ModifiedDoc{
-				Old:   doc1,
-				New:   doc2,
-				Diffs: diffs,
+			// Modified, unless the only differences are reordered keyed list elements or ignored paths
+			changes := parser.CompareDocuments(doc1, doc2, e.compareOptionsFor(doc1, doc2))
+			if len(changes) > 0 {
+				result.Modified[key] = ModifiedDoc{
+					Old:     doc1,
+					New:     doc2,
+					Changes: changes,
+				}
 			}
 		}
 	}
 
-	return result
+	return result, nil
 }
 
-func (e *Engine) makeDocMap(docs []parser.Document) map[string]parser.Document {
+// makeDocMap keys documents by identity. Documents without an identity and
+// documents whose identity is in qualified are keyed per source file, with source
+// files renamed according to renames so documents of renamed files get the keys
+// of their new path.
+func (e *Engine) makeDocMap(docs []parser.Document, renames map[string]string, qualified map[string]bool) (map[string]parser.Document, error) {
 	result := make(map[string]parser.Document)
+	positions := make(map[string]int)   // Position of the document within its source file
+	fileIndexes := make(map[string]int) // Position of the next document within its source file
+
+	for _, doc := range docs {
+		index := fileIndexes[doc.SourceFile]
+		fileIndexes[doc.SourceFile]++
 
-	for i, doc := range docs {
-		key := parser.ExtractKey(doc.Content, e.identifierPath)
+		identity := e.identity(doc)
+		key := identity
+		if key == "" {
+			// Fallback to the index within the source file if no identifier found
+			key = fmt.Sprintf("__index_%d__", index)
+		}
 
 		// Check for SourceFile to handle duplicate names across files
-		if doc.SourceFile != "" {
-		 // Append source file to key to make it unique
-		key = key + " (from " + doc.SourceFile + ")"
+		if doc.SourceFile != "" && (identity == "" || qualified[identity]) {
+			// Append source file to key to make it unique
+			sourceFile := doc.SourceFile
+			if renamed, ok := renames[sourceFile]; ok {
+				sourceFile = renamed
+			}
+			key = key + " (from " + sourceFile + ")"
 		}
 
-		if key == "" {
-			// Fallback to index if no identifier found
-			key = fmt.Sprintf("__index_%d__", i)
+		if prev, exists := positions[key]; exists {
+			return nil, &DuplicateIdentityError{
+				Identity:   identity,
+				SourceFile: doc.SourceFile,
+				First:      prev + 1,
+				Second:     index + 1,
+			}
 		}
+		positions[key] = index
+
 		doc.Key = key
 		result[key] = doc
 	}
 
-	return result
+	return result, nil
 }
 
-// HasDifferences returns true if there are any differences
-func (r *Result) HasDifferences() bool {
-	return len(r.Added) > 0 || len(r.Deleted) > 0 || len(r.Modified) > 0
-}
-
-// Print prints the diff result
-func (r *Result) Print(verbose bool) {
-	red := color.New(color.FgRed).SprintFunc()
-	green := color.New(color.FgGreen).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
-	cyan := color.New(color.FgCyan).SprintFunc()
-
-	if !verbose {
-		// Non-verbose: show key names only
-		// Print added documents
-		keys := sortedKeys(r.Added)
-		for _, key := range keys {
-			fmt.Printf("%s %s\n", green("+ Added:"), cyan(key))
-		}
-
-		// Print deleted documents
-		keys = sortedKeys(r.Deleted)
-		for _, key := range keys {
-			fmt.Printf("%s %s\n", red("- Deleted:"), cyan(key))
-		}
+// duplicateIdentities returns the identities shared by documents in different
+// files on either side, which are then matched per file.
+// With strict identity, such documents are an error instead.
+func (e *Engine) duplicateIdentities(docs1, docs2 []parser.Document) (map[string]bool, error) {
+	qualified := make(map[string]bool)
 
-		// Print modified documents
-		keys = sortedKeysModified(r.Modified)
-		for _, key := range keys {
-			mod := r.Modified[key]
-			fmt.Printf("%s %s\n", yellow("~ Modified:"), cyan(key))
-			for _, diff := range mod.Diffs {
-				fmt.Printf("  %s\n", diff)
+	for _, docs := range [][]parser.Document{docs1, docs2} {
+		files := make(map[string]string) // identity → first source file
+		for _, doc := range docs {
+			identity := e.identity(doc)
+			if identity == "" || doc.SourceFile == "" {
+				continue
 			}
-			fmt.Println()
-		}
-
-		// Print summary
-		r.PrintSummary()
-	} else {
-		// Verbose: show summary first, then full document content with diff-style prefixes
-		r.PrintSummaryCompact()
-
-		// Print added documents with "+" prefix
-		keys := sortedKeys(r.Added)
-		for _, key := range keys {
-			doc := r.Added[key]
-			lines := parser.SplitLines(doc.Raw)
-			for _, line := range lines {
-				if len(line) > 0 {
-					fmt.Printf("%s\n", green("+ "+line))
-				}
+			first, seen := files[identity]
+			if !seen {
+				files[identity] = doc.SourceFile
+				continue
 			}
-		}
-
-		// Print deleted documents with "-" prefix
-		keys = sortedKeys(r.Deleted)
-		for _, key := range keys {
-			doc := r.Deleted[key]
-			lines := parser.SplitLines(doc.Raw)
-			for _, line := range lines {
-				if len(line) > 0 {
-					fmt.Printf("%s\n", red("- "+line))
-				}
+			if first == doc.SourceFile {
+				continue // Duplicates within a file are reported by makeDocMap
 			}
+			if e.strictIdentity {
+				return nil, fmt.Errorf("documents in %s and %s have the same identity %q", first, doc.SourceFile, identity)
+			}
+			qualified[identity] = true
 		}
+	}
 
-		// Print modified documents
-		keys = sortedKeysModified(r.Modified)
-		for _, key := range keys {
-			mod := r.Modified[key]
-			fmt.Printf("%s %s\n", yellow("~ Modified:"), cyan(key))
-			for _, diff := range mod.Diffs {
-				fmt.Printf("  %s\n", diff)
-			}
-			fmt.Println()
+	return qualified, nil
+}
+
+// renamed returns the path of a file after the renames set with SetFileRenames
+func (e *Engine) renamed(path string) string {
+	if renamed, ok := e.fileRenames[path]; ok {
+		return renamed
+	}
+	return path
+}
+
+// identityEscaper escapes the separator in identity parts after the first
+var identityEscaper = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// identity joins the values found at the identifier paths with "/", or returns ""
+// if none has a value. Paths with no value (e.g. the namespace of a cluster-scoped
+// resource) are kept as empty parts, and "/" in values after the first is escaped,
+// so that different tuples never share an identity. The first value is not escaped,
+// keeping apiVersion readable with the k8s preset.
+func (e *Engine) identity(doc parser.Document) string {
+	parts := make([]string, len(e.identifierPaths))
+	found := false
+	for i, path := range e.identifierPaths {
+		value := parser.ExtractKey(doc.Content, path)
+		if value != "" {
+			found = true
+		}
+		if i > 0 {
+			value = identityEscaper.Replace(value)
 		}
+		parts[i] = value
 	}
+	if !found {
+		return ""
+	}
+	return strings.Join(parts, "/")
+}
+
+// HasDifferences returns true if there are any differences
+func (r *Result) HasDifferences() bool {
+	return len(r.Added) > 0 || len(r.Deleted) > 0 || len(r.Modified) > 0 || len(r.Moved) > 0
+}
+
+// AddedKeys returns the keys of added documents in sorted order
+func (r *Result) AddedKeys() []string {
+	return sortedKeys(r.Added)
 }
 
-// PrintSummary prints a summary of changes
-func (r *Result) PrintSummary() {
-	green := color.New(color.FgGreen).SprintFunc()
-	red := color.New(color.FgRed).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
-	bold := color.New(color.Bold).SprintFunc()
+// DeletedKeys returns the keys of deleted documents in sorted order
+func (r *Result) DeletedKeys() []string {
+	return sortedKeys(r.Deleted)
+}
 
-	fmt.Printf("\n%s\n", bold("Summary:"))
-	fmt.Printf("  %s: %d\n", green("Added"), len(r.Added))
-	fmt.Printf("  %s: %d\n", red("Deleted"), len(r.Deleted))
-	fmt.Printf("  %s: %d\n", yellow("Modified"), len(r.Modified))
+// ModifiedKeys returns the keys of modified documents in sorted order
+func (r *Result) ModifiedKeys() []string {
+	return sortedKeysModified(r.Modified)
 }
 
-// PrintSummaryCompact prints a compact summary suitable for verbose output
-func (r *Result) PrintSummaryCompact() {
-	fmt.Printf("Summary\n")
-	fmt.Printf("%d added, %d deleted, %d modified\n", len(r.Added), len(r.Deleted), len(r.Modified))
+// MovedKeys returns the keys of moved documents in sorted order
+func (r *Result) MovedKeys() []string {
+	keys := make([]string, 0, len(r.Moved))
+	for k := range r.Moved {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func sortedKeys(m map[string]parser.Document) []string {
@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/tyuhara/yamlcmt/internal/parser"
+)
+
+func TestIdentity(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		content map[string]interface{}
+		want    string
+	}{
+		{
+			name:    "single path",
+			paths:   []string{"metadata.name"},
+			content: map[string]interface{}{"metadata": map[string]interface{}{"name": "a/b"}},
+			want:    "a/b",
+		},
+		{
+			name:  "k8s preset",
+			paths: []string{"k8s"},
+			content: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"namespace": "ns", "name": "api"},
+			},
+			want: "apps/v1/Deployment/ns/api",
+		},
+		{
+			name:  "missing namespace is kept as an empty part",
+			paths: []string{"k8s"},
+			content: map[string]interface{}{
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"kind":       "ClusterRole",
+				"metadata":   map[string]interface{}{"name": "admin"},
+			},
+			want: "rbac.authorization.k8s.io/v1/ClusterRole//admin",
+		},
+		{
+			name:    "separator in later values is escaped",
+			paths:   []string{"kind", "name"},
+			content: map[string]interface{}{"kind": "a", "name": "b/c%"},
+			want:    "a/b%2Fc%25",
+		},
+		{
+			name:    "no value",
+			paths:   []string{"kind", "name"},
+			content: map[string]interface{}{"other": "x"},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewEngine(tt.paths).identity(parser.Document{Content: tt.content})
+			if got != tt.want {
+				t.Errorf("identity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentityNoCollisions(t *testing.T) {
+	docs := []map[string]interface{}{
+		{"kind": "a/b", "name": "c"},
+		{"kind": "a", "name": "b/c"},
+		{"namespace": "ns", "name": "x"},
+		{"name": "ns", "other": "x"},
+		{"kind": "k", "name": "ns/x"},
+		{"kind": "k", "namespace": "ns", "name": "x"},
+	}
+	engine := NewEngine([]string{"kind", "namespace", "name"})
+
+	seen := make(map[string]int)
+	for i, content := range docs {
+		identity := engine.identity(parser.Document{Content: content})
+		if j, ok := seen[identity]; ok {
+			t.Errorf("documents %v and %v share the identity %q", docs[j], content, identity)
+		}
+		seen[identity] = i
+	}
+}
@@ -0,0 +1,138 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tyuhara/yamlcmt/internal/parser"
+)
+
+// IgnoreRule excludes changes at matching paths from the comparison.
+// Path is a dot-notation glob where "*" matches within a single path segment
+// and "**" matches across segments, e.g. "metadata.annotations.**" or "**.status".
+// Matching a path also ignores everything below it.
+type IgnoreRule struct {
+	Path  string
+	Kinds []string // Only apply to documents of these kinds (all kinds if empty)
+}
+
+// ParseIgnoreRule parses a rule in the form "path" or "Kind:path"
+func ParseIgnoreRule(s string) (IgnoreRule, error) {
+	rule := IgnoreRule{Path: s}
+	if kind, path, found := strings.Cut(s, ":"); found {
+		rule = IgnoreRule{Path: path, Kinds: []string{kind}}
+	}
+
+	if rule.Path == "" {
+		return IgnoreRule{}, fmt.Errorf("invalid ignore rule %q: empty path", s)
+	}
+	return rule, nil
+}
+
+// PathMatcher matches change paths against a dot-notation glob,
+// using the same syntax as IgnoreRule.Path
+type PathMatcher struct {
+	pattern *regexp.Regexp
+}
+
+// NewPathMatcher compiles a dot-notation path glob
+func NewPathMatcher(glob string) (*PathMatcher, error) {
+	pattern, err := regexp.Compile(globToRegexp(glob))
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", glob, err)
+	}
+	return &PathMatcher{pattern: pattern}, nil
+}
+
+// Match reports whether the path matches the glob
+func (m *PathMatcher) Match(path string) bool {
+	return m.pattern.MatchString(path)
+}
+
+// compiledIgnoreRule is an IgnoreRule with its glob compiled
+type compiledIgnoreRule struct {
+	matcher *PathMatcher
+	kinds   map[string]bool
+}
+
+// SetIgnoreRules configures the paths whose changes are ignored.
+// Documents that differ only in ignored paths are not reported as modified.
+func (e *Engine) SetIgnoreRules(rules []IgnoreRule) error {
+	compiled := make([]compiledIgnoreRule, 0, len(rules))
+	for _, rule := range rules {
+		matcher, err := NewPathMatcher(rule.Path)
+		if err != nil {
+			return fmt.Errorf("invalid ignore rule: %w", err)
+		}
+
+		var kinds map[string]bool
+		if len(rule.Kinds) > 0 {
+			kinds = make(map[string]bool, len(rule.Kinds))
+			for _, kind := range rule.Kinds {
+				kinds[kind] = true
+			}
+		}
+
+		compiled = append(compiled, compiledIgnoreRule{matcher: matcher, kinds: kinds})
+	}
+
+	e.ignoreRules = compiled
+	return nil
+}
+
+// compareOptionsFor returns the compare options for a pair of documents,
+// with the ignore rules scoped to their kinds
+func (e *Engine) compareOptionsFor(oldDoc, newDoc parser.Document) parser.CompareOptions {
+	opts := e.compareOptions
+	if len(e.ignoreRules) == 0 {
+		return opts
+	}
+
+	oldKind := parser.ExtractKey(oldDoc.Content, "kind")
+	newKind := parser.ExtractKey(newDoc.Content, "kind")
+
+	var matchers []*PathMatcher
+	for _, rule := range e.ignoreRules {
+		if rule.kinds == nil || rule.kinds[oldKind] || rule.kinds[newKind] {
+			matchers = append(matchers, rule.matcher)
+		}
+	}
+
+	opts.Ignore = func(path string) bool {
+		for _, matcher := range matchers {
+			if matcher.Match(path) {
+				return true
+			}
+		}
+		return false
+	}
+	return opts
+}
+
+// globToRegexp converts a dot-notation path glob to an anchored regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**."):
+			// Zero or more leading segments
+			b.WriteString(`(?:.*\.)?`)
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString(`[^.]*`)
+		case glob[i] == '?':
+			b.WriteString(`[^.]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/tyuhara/yamlcmt/internal/parser"
+)
+
+// JSONSchemaVersion is the version of the JSON output schema.
+// It is incremented whenever a field is removed or its meaning changes.
+const JSONSchemaVersion = 1
+
+// jsonResult is the top-level object of the JSON output
+type jsonResult struct {
+	SchemaVersion int            `json:"schema_version"`
+	HasChanges    bool           `json:"has_changes"`
+	Summary       jsonSummary    `json:"summary"`
+	Added         []jsonDocument `json:"added"`
+	Deleted       []jsonDocument `json:"deleted"`
+	Modified      []jsonModified `json:"modified"`
+	Moved         []jsonModified `json:"moved"`
+}
+
+type jsonSummary struct {
+	Added    int `json:"added"`
+	Deleted  int `json:"deleted"`
+	Modified int `json:"modified"`
+	Moved    int `json:"moved"`
+}
+
+type jsonDocument struct {
+	Key        string                 `json:"key"`
+	SourceFile string                 `json:"source_file,omitempty"`
+	Content    map[string]interface{} `json:"content"`
+}
+
+type jsonModified struct {
+	Key           string       `json:"key"`
+	OldSourceFile string       `json:"old_source_file,omitempty"`
+	NewSourceFile string       `json:"new_source_file,omitempty"`
+	Changes       []jsonChange `json:"changes"`
+}
+
+type jsonChange struct {
+	Type     parser.ChangeType `json:"type"`
+	Path     string            `json:"path"`
+	OldValue *jsonValue        `json:"old_value,omitempty"`
+	NewValue *jsonValue        `json:"new_value,omitempty"`
+	Line     int               `json:"line,omitempty"`
+	Column   int               `json:"column,omitempty"`
+}
+
+// jsonValue is a YAML value that is written even when it is null, so that a null
+// value can be told apart from a missing one
+type jsonValue struct {
+	value interface{}
+}
+
+func (v *jsonValue) MarshalJSON() ([]byte, error) {
+	// Not json.Marshal, which would escape HTML unlike the rest of the output
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v.value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// WriteJSON writes the result as JSON following schema JSONSchemaVersion.
+// Documents are sorted by key so the output is stable across runs.
+func (r *Result) WriteJSON(w io.Writer) error {
+	out := jsonResult{
+		SchemaVersion: JSONSchemaVersion,
+		HasChanges:    r.HasDifferences(),
+		Summary: jsonSummary{
+			Added:    len(r.Added),
+			Deleted:  len(r.Deleted),
+			Modified: len(r.Modified),
+			Moved:    len(r.Moved),
+		},
+		Added:    jsonDocuments(r.Added),
+		Deleted:  jsonDocuments(r.Deleted),
+		Modified: make([]jsonModified, 0, len(r.Modified)),
+		Moved:    make([]jsonModified, 0, len(r.Moved)),
+	}
+
+	for _, key := range sortedKeysModified(r.Modified) {
+		mod := r.Modified[key]
+		out.Modified = append(out.Modified, jsonModified{
+			Key:           key,
+			OldSourceFile: mod.Old.SourceFile,
+			NewSourceFile: mod.New.SourceFile,
+			Changes:       jsonChanges(mod.Changes),
+		})
+	}
+	for _, key := range r.MovedKeys() {
+		moved := r.Moved[key]
+		out.Moved = append(out.Moved, jsonModified{
+			Key:           key,
+			OldSourceFile: moved.Old.SourceFile,
+			NewSourceFile: moved.New.SourceFile,
+			Changes:       jsonChanges(moved.Changes),
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(out)
+}
+
+func jsonDocuments(m map[string]parser.Document) []jsonDocument {
+	docs := make([]jsonDocument, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		doc := m[key]
+		docs = append(docs, jsonDocument{
+			Key:        key,
+			SourceFile: doc.SourceFile,
+			Content:    doc.Content,
+		})
+	}
+	return docs
+}
+
+func jsonChanges(changes []parser.Change) []jsonChange {
+	out := make([]jsonChange, 0, len(changes))
+	for _, change := range changes {
+		c := jsonChange{
+			Type:   change.Type,
+			Path:   change.Path,
+			Line:   change.Line,
+			Column: change.Column,
+		}
+		// Added fields have no old value and deleted fields no new value
+		if change.Type != parser.ChangeAdded {
+			c.OldValue = &jsonValue{change.Old}
+		}
+		if change.Type != parser.ChangeDeleted {
+			c.NewValue = &jsonValue{change.New}
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/tyuhara/yamlcmt/internal/parser"
+)
+
+// Renderer writes a human-readable diff result to an io.Writer.
+// Color output is decided per renderer, so the terminal output and the
+// details embedded in a PR comment can be rendered independently.
+type Renderer struct {
+	w      io.Writer
+	red    func(a ...interface{}) string
+	green  func(a ...interface{}) string
+	yellow func(a ...interface{}) string
+	cyan   func(a ...interface{}) string
+	bold   func(a ...interface{}) string
+
+	unified      bool
+	contextLines int
+}
+
+// NewRenderer creates a renderer that writes to w, with or without color
+func NewRenderer(w io.Writer, useColor bool) *Renderer {
+	colorFunc := func(attr color.Attribute) func(a ...interface{}) string {
+		c := color.New(attr)
+		if useColor {
+			c.EnableColor()
+		} else {
+			c.DisableColor()
+		}
+		return c.SprintFunc()
+	}
+
+	return &Renderer{
+		w:      w,
+		red:    colorFunc(color.FgRed),
+		green:  colorFunc(color.FgGreen),
+		yellow: colorFunc(color.FgYellow),
+		cyan:   colorFunc(color.FgCyan),
+		bold:   colorFunc(color.Bold),
+	}
+}
+
+// UseUnifiedFormat renders modified documents as a unified diff of their YAML
+// with the given number of context lines, instead of field-level changes
+func (p *Renderer) UseUnifiedFormat(contextLines int) {
+	p.unified = true
+	p.contextLines = contextLines
+}
+
+// Render writes the diff result
+func (p *Renderer) Render(r *Result, verbose bool) {
+	if !verbose {
+		// Non-verbose: show key names only
+		// Print added documents
+		keys := sortedKeys(r.Added)
+		for _, key := range keys {
+			fmt.Fprintf(p.w, "%s %s\n", p.green("+ Added:"), p.cyan(key))
+		}
+
+		// Print deleted documents
+		keys = sortedKeys(r.Deleted)
+		for _, key := range keys {
+			fmt.Fprintf(p.w, "%s %s\n", p.red("- Deleted:"), p.cyan(key))
+		}
+
+		// Print modified and moved documents
+		p.renderModified(r)
+		p.renderMoved(r)
+
+		// Print summary
+		p.RenderSummary(r)
+	} else {
+		// Verbose: show summary first, then full document content with diff-style prefixes
+		p.RenderSummaryCompact(r)
+
+		// Print added documents with "+" prefix
+		keys := sortedKeys(r.Added)
+		for _, key := range keys {
+			doc := r.Added[key]
+			lines := parser.SplitLines(doc.Raw)
+			for _, line := range lines {
+				if len(line) > 0 {
+					fmt.Fprintf(p.w, "%s\n", p.green("+ "+line))
+				}
+			}
+		}
+
+		// Print deleted documents with "-" prefix
+		keys = sortedKeys(r.Deleted)
+		for _, key := range keys {
+			doc := r.Deleted[key]
+			lines := parser.SplitLines(doc.Raw)
+			for _, line := range lines {
+				if len(line) > 0 {
+					fmt.Fprintf(p.w, "%s\n", p.red("- "+line))
+				}
+			}
+		}
+
+		// Print modified and moved documents
+		p.renderModified(r)
+		p.renderMoved(r)
+	}
+}
+
+// renderModified writes each modified document with its field-level changes
+func (p *Renderer) renderModified(r *Result) {
+	keys := sortedKeysModified(r.Modified)
+	for _, key := range keys {
+		mod := r.Modified[key]
+		if p.unified {
+			p.renderUnified(key, mod)
+			continue
+		}
+
+		fmt.Fprintf(p.w, "%s %s\n", p.yellow("~ Modified:"), p.cyan(key))
+		for _, change := range mod.Changes {
+			fmt.Fprintf(p.w, "  %s\n", change)
+		}
+		fmt.Fprintln(p.w)
+	}
+}
+
+// renderMoved writes each moved document with its source files and any field-level changes
+func (p *Renderer) renderMoved(r *Result) {
+	for _, key := range r.MovedKeys() {
+		moved := r.Moved[key]
+		fmt.Fprintf(p.w, "%s %s (%s → %s)\n", p.cyan("→ Moved:"), p.cyan(key), moved.Old.SourceFile, moved.New.SourceFile)
+		if p.unified && len(moved.Changes) > 0 {
+			p.renderUnified(key, ModifiedDoc(moved))
+			continue
+		}
+		for _, change := range moved.Changes {
+			fmt.Fprintf(p.w, "  %s\n", change)
+		}
+		if len(moved.Changes) > 0 {
+			fmt.Fprintln(p.w)
+		}
+	}
+}
+
+// renderUnified writes a modified document as a unified diff of Old.Raw and New.Raw
+func (p *Renderer) renderUnified(key string, mod ModifiedDoc) {
+	text := UnifiedDiff("a/"+key, "b/"+key, mod.Old.Raw, mod.New.Raw, p.contextLines)
+	for _, line := range parser.SplitLines(text) {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			fmt.Fprintln(p.w, p.bold(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Fprintln(p.w, p.cyan(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprintln(p.w, p.red(line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Fprintln(p.w, p.green(line))
+		default:
+			fmt.Fprintln(p.w, line)
+		}
+	}
+	fmt.Fprintln(p.w)
+}
+
+// RenderSummary writes a summary of changes
+func (p *Renderer) RenderSummary(r *Result) {
+	fmt.Fprintf(p.w, "\n%s\n", p.bold("Summary:"))
+	fmt.Fprintf(p.w, "  %s: %d\n", p.green("Added"), len(r.Added))
+	fmt.Fprintf(p.w, "  %s: %d\n", p.red("Deleted"), len(r.Deleted))
+	fmt.Fprintf(p.w, "  %s: %d\n", p.yellow("Modified"), len(r.Modified))
+	if len(r.Moved) > 0 {
+		fmt.Fprintf(p.w, "  %s: %d\n", p.cyan("Moved"), len(r.Moved))
+	}
+}
+
+// RenderSummaryCompact writes a compact summary suitable for verbose output
+func (p *Renderer) RenderSummaryCompact(r *Result) {
+	fmt.Fprintf(p.w, "Summary\n")
+	fmt.Fprintf(p.w, "%d added, %d deleted, %d modified", len(r.Added), len(r.Deleted), len(r.Modified))
+	if len(r.Moved) > 0 {
+		fmt.Fprintf(p.w, ", %d moved", len(r.Moved))
+	}
+	fmt.Fprintln(p.w)
+}
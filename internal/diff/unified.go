@@ -0,0 +1,254 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lineOp is a single line of an edit script between two texts
+type lineOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// UnifiedDiff returns a unified diff of two texts with the given number of context lines.
+// The result starts with "---"/"+++" headers followed by "@@" hunks, as produced by `git diff`.
+// An empty string is returned if the texts are identical.
+func UnifiedDiff(oldLabel, newLabel, oldText, newText string, context int) string {
+	ops := diffLines(textLines(oldText), textLines(newText))
+
+	var b strings.Builder
+	for _, h := range hunks(ops, context) {
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldLabel, newLabel)
+		}
+
+		// Line numbers of the first line of the hunk on each side
+		oldStart, newStart := 1, 1
+		for _, op := range ops[:h[0]] {
+			if op.kind != '+' {
+				oldStart++
+			}
+			if op.kind != '-' {
+				newStart++
+			}
+		}
+
+		oldCount, newCount := 0, 0
+		for _, op := range ops[h[0]:h[1]] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range ops[h[0]:h[1]] {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.text)
+		}
+	}
+
+	return b.String()
+}
+
+// hunkRange formats the start,count pair of a hunk header.
+// An empty range refers to the line before it, as in GNU diff.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// hunks groups changed lines with their surrounding context.
+// Each hunk is returned as a [start, end) range of indexes into ops.
+func hunks(ops []lineOp, context int) [][2]int {
+	var result [][2]int
+
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while the next change is within 2*context lines
+		end := i + 1
+		for j := end; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+
+		stop := end + context
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		result = append(result, [2]int{start, stop})
+		i = end - 1
+	}
+
+	return result
+}
+
+// diffLines computes a minimal line edit script with Myers' algorithm in linear space,
+// so that large documents do not need a quadratic table
+func diffLines(a, b []string) []lineOp {
+	ops := make([]lineOp, 0, len(a)+len(b))
+	return appendDiff(ops, a, b)
+}
+
+// appendDiff appends the edit script of a → b to ops
+func appendDiff(ops []lineOp, a, b []string) []lineOp {
+	// Common prefix and suffix are kept as is, which is most of a modified document
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, lineOp{' ', a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			ops = append(ops, lineOp{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			ops = append(ops, lineOp{'-', line})
+		}
+	default:
+		x, y, ok := bisect(a, b)
+		if ok {
+			ops = appendDiff(ops, a[:x], b[:y])
+			ops = appendDiff(ops, a[x:], b[y:])
+		} else {
+			for _, line := range a {
+				ops = append(ops, lineOp{'-', line})
+			}
+			for _, line := range b {
+				ops = append(ops, lineOp{'+', line})
+			}
+		}
+	}
+
+	for _, line := range common {
+		ops = append(ops, lineOp{' ', line})
+	}
+	return ops
+}
+
+// bisect finds where the forward and backward searches for the shortest edit script
+// of a → b meet, splitting it into two independent halves. a and b must not be empty.
+// ok is false if a and b have no line in common.
+func bisect(a, b []string) (x, y int, ok bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// v[offset+k] is the furthest x reached on diagonal k (x - y = k), from the start
+	// for vf and from the end for vb
+	vf := make([]int, 2*maxD+2)
+	vb := make([]int, 2*maxD+2)
+	for i := range vf {
+		vf[i] = -1
+		vb[i] = -1
+	}
+	vf[offset+1] = 0
+	vb[offset+1] = 0
+
+	delta := n - m
+	// With an odd delta the paths can only meet while searching forward
+	front := delta%2 != 0
+
+	// Diagonals that went past the end of a or b are not searched anymore
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			i := offset + k
+			var x1 int
+			if k == -d || (k != d && vf[i-1] < vf[i+1]) {
+				x1 = vf[i+1]
+			} else {
+				x1 = vf[i-1] + 1
+			}
+			y1 := x1 - k
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			vf[i] = x1
+
+			switch {
+			case x1 > n:
+				fEnd += 2
+			case y1 > m:
+				fStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(vb) && vb[j] != -1 && x1 >= n-vb[j] {
+					return x1, y1, true
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			i := offset + k
+			var x2 int
+			if k == -d || (k != d && vb[i-1] < vb[i+1]) {
+				x2 = vb[i+1]
+			} else {
+				x2 = vb[i-1] + 1
+			}
+			y2 := x2 - k
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			vb[i] = x2
+
+			switch {
+			case x2 > n:
+				bEnd += 2
+			case y2 > m:
+				bStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(vf) && vf[j] != -1 {
+					x1 := vf[j]
+					y1 := x1 - (delta - k)
+					if x1 >= n-x2 {
+						return x1, y1, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// textLines splits text into lines, treating empty text as no lines
+func textLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
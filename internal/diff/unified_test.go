@@ -0,0 +1,162 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns "l1\n" … "ln\n" with the given lines replaced
+func numberedLines(n int, replace map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, ok := replace[i]; ok {
+			if line != "" {
+				b.WriteString(line + "\n")
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "l%d\n", i)
+	}
+	return b.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		context int
+		want    string
+	}{
+		{
+			name:    "identical",
+			old:     numberedLines(5, nil),
+			new:     numberedLines(5, nil),
+			context: 3,
+			want:    "",
+		},
+		{
+			name:    "single change",
+			old:     numberedLines(10, nil),
+			new:     numberedLines(10, map[int]string{5: "x5"}),
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -2,7 +2,7 @@\n l2\n l3\n l4\n-l5\n+x5\n l6\n l7\n l8\n",
+		},
+		{
+			name:    "changes within twice the context are merged",
+			old:     numberedLines(12, nil),
+			new:     numberedLines(12, map[int]string{3: "x3", 10: "x10"}),
+			context: 3,
+			want: "--- old\n+++ new\n@@ -1,12 +1,12 @@\n l1\n l2\n-l3\n+x3\n l4\n l5\n l6\n l7\n l8\n l9\n" +
+				"-l10\n+x10\n l11\n l12\n",
+		},
+		{
+			name:    "changes further apart are separate hunks",
+			old:     numberedLines(12, nil),
+			new:     numberedLines(12, map[int]string{3: "x3", 10: "x10"}),
+			context: 2,
+			want:    "--- old\n+++ new\n@@ -1,5 +1,5 @@\n l1\n l2\n-l3\n+x3\n l4\n l5\n@@ -8,5 +8,5 @@\n l8\n l9\n-l10\n+x10\n l11\n l12\n",
+		},
+		{
+			name:    "no context",
+			old:     numberedLines(10, nil),
+			new:     numberedLines(10, map[int]string{5: "x5"}),
+			context: 0,
+			want:    "--- old\n+++ new\n@@ -5 +5 @@\n-l5\n+x5\n",
+		},
+		{
+			name:    "insertion without context",
+			old:     numberedLines(5, nil),
+			new:     numberedLines(5, map[int]string{3: "l3\nnew"}),
+			context: 0,
+			want:    "--- old\n+++ new\n@@ -3,0 +4 @@\n+new\n",
+		},
+		{
+			name:    "deletion without context",
+			old:     numberedLines(5, nil),
+			new:     numberedLines(5, map[int]string{3: ""}),
+			context: 0,
+			want:    "--- old\n+++ new\n@@ -3 +2,0 @@\n-l3\n",
+		},
+		{
+			name:    "new text",
+			old:     "",
+			new:     numberedLines(2, nil),
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+l1\n+l2\n",
+		},
+		{
+			name:    "large document",
+			old:     numberedLines(10000, nil),
+			new:     numberedLines(10000, map[int]string{5000: "x5000"}),
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -4999,3 +4999,3 @@\n l4999\n-l5000\n+x5000\n l5001\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("old", "new", tt.old, tt.new, tt.context)
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesMinimal checks that edit scripts of random texts rebuild both texts
+// and keep a longest common subsequence
+func TestDiffLinesMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		ops := diffLines(a, b)
+
+		var gotA, gotB []string
+		common := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				gotA = append(gotA, op.text)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.text)
+			}
+			if op.kind == ' ' {
+				common++
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("edit script of %q → %q does not rebuild the texts: %v", a, b, ops)
+		}
+		if want := lcsLength(a, b); common != want {
+			t.Fatalf("edit script of %q → %q keeps %d common lines, want %d", a, b, common, want)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs[0][0]
+}
@@ -0,0 +1,219 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fileVersion is a file at a ref, or in the working tree when ref is empty
+type fileVersion struct {
+	ref  string
+	path string
+	old  bool // Whether this is the old side of the comparison
+}
+
+// readFiles returns the content of each file version, or nil for versions that do
+// not exist at their ref. Paths are relative to the repository root. Versions in Git
+// are read with one `git ls-tree` per ref and a single `git cat-file --batch` process
+// instead of one `git show` per file.
+func readFiles(versions []fileVersion) ([][]byte, error) {
+	contents := make([][]byte, len(versions))
+
+	trees := make(map[string]map[string]string) // ref → path → blob id
+	var objects []string
+	var indexes []int
+	var root string
+	for i, v := range versions {
+		if v.ref == "" {
+			// Paths are relative to the repository root, not the current directory
+			if root == "" {
+				var err error
+				root, err = TopLevel()
+				if err != nil {
+					return nil, err
+				}
+			}
+			content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(v.path)))
+			if os.IsNotExist(err) {
+				continue // Deleted in the working tree
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", v.path, err)
+			}
+			contents[i] = content
+			continue
+		}
+
+		tree, ok := trees[v.ref]
+		if !ok {
+			var err error
+			tree, err = listTree(v.ref)
+			if err != nil {
+				return nil, err
+			}
+			trees[v.ref] = tree
+		}
+		// Looking up blobs by id is much faster than by <ref>:<path> in large trees
+		if id, ok := tree[v.path]; ok {
+			objects = append(objects, id)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(objects) == 0 {
+		return contents, nil
+	}
+
+	blobs, err := catFiles(objects)
+	if err != nil {
+		return nil, err
+	}
+	for j, blob := range blobs {
+		contents[indexes[j]] = blob
+	}
+
+	return contents, nil
+}
+
+// listTree returns the blob id of every file at a ref, by path from the repository root
+func listTree(ref string) (map[string]string, error) {
+	cmd := exec.Command("git", "ls-tree", "-r", "-z", "--full-tree", ref)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to list files at %s: %w\nStderr: %s", ref, err, string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("failed to list files at %s: %w", ref, err)
+	}
+
+	tree := make(map[string]string)
+	for _, entry := range strings.Split(string(output), "\x00") {
+		// <mode> SP <type> SP <id> TAB <path>
+		info, path, found := strings.Cut(entry, "\t")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(info)
+		if len(fields) == 3 && fields[1] == "blob" {
+			tree[path] = fields[2]
+		}
+	}
+	return tree, nil
+}
+
+// catFiles returns the content of each object, or nil for objects that do not
+// exist or are not blobs
+func catFiles(objects []string) ([][]byte, error) {
+	cmd := exec.Command("git", "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+
+	// Requests are written concurrently so that git never blocks on a full stdout pipe
+	go func() {
+		w := bufio.NewWriter(stdin)
+		for _, object := range objects {
+			fmt.Fprintln(w, object)
+		}
+		w.Flush()
+		stdin.Close()
+	}()
+
+	blobs, readErr := readBatch(bufio.NewReader(stdout), len(objects))
+	if readErr != nil {
+		cmd.Process.Kill()
+	}
+	if err := cmd.Wait(); err != nil && readErr == nil {
+		readErr = err
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read files from git: %w\nStderr: %s", readErr, stderr.String())
+	}
+
+	return blobs, nil
+}
+
+// readBatch reads n responses of `git cat-file --batch`: "<oid> <type> <size>" followed
+// by the content, or "<object> missing"
+func readBatch(r *bufio.Reader, n int) ([][]byte, error) {
+	blobs := make([][]byte, n)
+	for i := range blobs {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		header = strings.TrimSuffix(header, "\n")
+		if strings.HasSuffix(header, " missing") || strings.HasSuffix(header, " ambiguous") {
+			continue
+		}
+
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected git cat-file output: %q", header)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected git cat-file output: %q", header)
+		}
+
+		content := make([]byte, size)
+		if _, err := io.ReadFull(r, content); err != nil {
+			return nil, err
+		}
+		if _, err := r.Discard(1); err != nil { // Trailing newline
+			return nil, err
+		}
+		if fields[1] == "blob" {
+			blobs[i] = content
+		}
+	}
+	return blobs, nil
+}
+
+// parallel calls fn for 0..n-1 on up to GOMAXPROCS goroutines.
+// The error of the lowest index is returned, so errors do not depend on scheduling.
+func parallel(n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.GOMAXPROCS(0), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
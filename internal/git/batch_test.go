@@ -0,0 +1,79 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// benchmarkFiles is the number of manifests changed between the two refs
+const benchmarkFiles = 3000
+
+// BenchmarkParseChangedFiles reads and parses thousands of manifests changed
+// between two commits of a synthetic repository
+func BenchmarkParseChangedFiles(b *testing.B) {
+	if _, err := exec.LookPath("git"); err != nil {
+		b.Skip("git not found")
+	}
+
+	b.Chdir(b.TempDir())
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=yamlcmt", "GIT_AUTHOR_EMAIL=yamlcmt@example.com",
+			"GIT_COMMITTER_NAME=yamlcmt", "GIT_COMMITTER_EMAIL=yamlcmt@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			b.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	writeManifests := func(replicas int) {
+		for i := 0; i < benchmarkFiles; i++ {
+			path := filepath.Join("manifests", fmt.Sprintf("app-%04d.yaml", i))
+			manifest := fmt.Sprintf("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app-%d\nspec:\n  replicas: %d\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: app-%d\n", i, replicas, i)
+			if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	if err := os.Mkdir("manifests", 0o755); err != nil {
+		b.Fatal(err)
+	}
+	run("init", "--quiet")
+	writeManifests(1)
+	run("add", ".")
+	run("commit", "--quiet", "-m", "base")
+	run("tag", "base")
+	writeManifests(2)
+	run("commit", "--quiet", "-a", "-m", "head")
+
+	files, err := GetChangedFiles("base", "HEAD")
+	if err != nil {
+		b.Fatal(err)
+	}
+	if len(files) != benchmarkFiles {
+		b.Fatalf("got %d changed files, want %d", len(files), benchmarkFiles)
+	}
+
+	// Progress is printed for every file
+	stderr := os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stderr = devNull
+	defer func() { os.Stderr = stderr }()
+
+	for b.Loop() {
+		oldDocs, newDocs, err := ParseChangedFiles("base", "HEAD", files)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(oldDocs) != 2*benchmarkFiles || len(newDocs) != 2*benchmarkFiles {
+			b.Fatalf("got %d old and %d new documents", len(oldDocs), len(newDocs))
+		}
+	}
+}
@@ -1,21 +1,42 @@
 package git
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
-	
+	"path/filepath"
+	"strings"
+
 	"github.com/tyuhara/yamlcmt/internal/parser"
-	"gopkg.in/yaml.v3"
 )
 
-// GetChangedYAMLFiles returns all changed YAML files compared to the specified branch.
-// It uses `git diff --name-only` to detect changes and filters for .yaml and .yml files.
-// Deleted files are excluded from the results.
-func GetChangedYAMLFiles(branch string) ([]string, error) {
-	cmd := exec.Command("git", "diff", "--name-only", branch)
+// File statuses of ChangedFile, as reported by `git diff --name-status`
+const (
+	FileAdded    = "A"
+	FileModified = "M"
+	FileDeleted  = "D"
+	FileRenamed  = "R"
+)
+
+// ChangedFile is a YAML file changed between two refs
+type ChangedFile struct {
+	Status  string // FileAdded, FileModified, FileDeleted or FileRenamed
+	Path    string // Path at head (at base for deleted files)
+	OldPath string // Path at base for renamed files
+}
+
+// GetChangedFiles returns the YAML files changed between two refs, including
+// deleted and renamed files. An empty head compares against the working tree.
+// A file renamed to or from a non-YAML name is reported as added or deleted.
+func GetChangedFiles(base, head string) ([]ChangedFile, error) {
+	// -z keeps paths unquoted, as Git otherwise quotes non-ASCII characters
+	args := []string{"diff", "-z", "--name-status", "-M", base}
+	if head != "" {
+		args = append(args, head)
+	}
+	args = append(args, "--")
+
+	cmd := exec.Command("git", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -24,221 +45,302 @@ func GetChangedYAMLFiles(branch string) ([]string, error) {
 		return nil, fmt.Errorf("failed to get changed files from git: %w", err)
 	}
 
-	var yamlFiles []string
-	lines := bytes.Split(output, []byte("\n"))
-	for _, line := range lines {
-		filename := string(bytes.TrimSpace(line))
-		if filename == "" {
-			continue
+	return parseNameStatus(string(output))
+}
+
+// parseNameStatus parses the output of `git diff -z --name-status`: <status>\0<path>\0,
+// or R<score>\0<old path>\0<new path>\0 for renames
+func parseNameStatus(output string) ([]ChangedFile, error) {
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	if len(fields) == 1 && fields[0] == "" {
+		return nil, nil
+	}
+
+	var files []ChangedFile
+	for i := 0; i < len(fields); {
+		status := fields[i]
+		paths := 1
+		if strings.HasPrefix(status, FileRenamed) || strings.HasPrefix(status, "C") {
+			paths = 2
+		}
+		if status == "" || i+paths >= len(fields) {
+			return nil, fmt.Errorf("unexpected git diff output: %q", output)
 		}
+		path := fields[i+1]
+		i += 1 + paths
 
-		// Check if it's a YAML file
-		if bytes.HasSuffix(line, []byte(".yaml")) || bytes.HasSuffix(line, []byte(".yml")) {
-			// Check if file exists (not deleted)
-			if _, err := os.Stat(filename); err == nil {
-				yamlFiles = append(yamlFiles, filename)
+		switch status[:1] {
+		case FileRenamed:
+			oldPath, newPath := path, fields[i-1]
+			switch {
+			case isYAML(oldPath) && isYAML(newPath):
+				files = append(files, ChangedFile{Status: FileRenamed, Path: newPath, OldPath: oldPath})
+			case isYAML(newPath):
+				files = append(files, ChangedFile{Status: FileAdded, Path: newPath})
+			case isYAML(oldPath):
+				files = append(files, ChangedFile{Status: FileDeleted, Path: oldPath})
+			}
+		case "C":
+			// Copies are only reported with -C, the copy is a new file
+			if newPath := fields[i-1]; isYAML(newPath) {
+				files = append(files, ChangedFile{Status: FileAdded, Path: newPath})
+			}
+		case FileAdded, FileDeleted:
+			if isYAML(path) {
+				files = append(files, ChangedFile{Status: status[:1], Path: path})
+			}
+		default:
+			// Modified, type changed or unmerged
+			if isYAML(path) {
+				files = append(files, ChangedFile{Status: FileModified, Path: path})
 			}
 		}
 	}
 
-	return yamlFiles, nil
+	return files, nil
 }
 
-// CombineFilesForComparison combines multiple YAML files from Git and current state for comparison.
-// It creates two temporary files:
-// - oldPath: Contains files from the specified branch
-// - newPath: Contains current versions of the files
-// Files are separated by "---" (YAML document separator).
-// Empty documents and comment-only sections are skipped to avoid __index__ artifacts.
-// The cleanup function should be called to remove temporary files after use.
-func CombineFilesForComparison(branch string, files []string) (oldPath, newPath string, cleanup func(), err error) {
-	// Create temporary files
-	tmpOld, err := os.CreateTemp("", "yamlcmt-old-*.yaml")
-	if err != nil {
-		return "", "", nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpOldPath := tmpOld.Name()
-
-	tmpNew, err := os.CreateTemp("", "yamlcmt-new-*.yaml")
-	if err != nil {
-		tmpOld.Close()
-		os.Remove(tmpOldPath)
-		return "", "", nil, fmt.Errorf("failed to create temp file: %w", err)
+// Renames returns the old path → new path of the renamed files
+func Renames(files []ChangedFile) map[string]string {
+	renames := make(map[string]string)
+	for _, file := range files {
+		if file.Status == FileRenamed {
+			renames[file.OldPath] = file.Path
+		}
 	}
-	tmpNewPath := tmpNew.Name()
-
-	// Combine files
-	for i, file := range files {
-		fmt.Fprintf(os.Stderr, "Processing: %s\n", file)
+	return renames
+}
 
-		// Get old version from Git
-		cmd := exec.Command("git", "show", fmt.Sprintf("%s:%s", branch, file))
-		output, err := cmd.Output()
-		if err == nil {
-			// File exists in the branch - clean the content
-			cleanedOld := cleanYAMLContent(output)
-			if len(cleanedOld) > 0 {
-				// Add separator if not the first file
-				if i > 0 && tmpOld != nil {
-					tmpOld.WriteString("---\n")
-				}
-				if _, err := tmpOld.Write(cleanedOld); err != nil {
-					tmpOld.Close()
-					tmpNew.Close()
-					os.Remove(tmpOldPath)
-					os.Remove(tmpNewPath)
-					return "", "", nil, fmt.Errorf("failed to write old file content: %w", err)
-				}
-				// Ensure the file ends with a newline
-				if cleanedOld[len(cleanedOld)-1] != '\n' {
-					tmpOld.WriteString("\n")
-				}
+// FilterChangedFiles returns the changed files whose path is selected by match.
+// A renamed file whose old or new path is not selected is kept as deleted or added.
+func FilterChangedFiles(files []ChangedFile, match func(path string) bool) []ChangedFile {
+	var filtered []ChangedFile
+	for _, file := range files {
+		if file.Status != FileRenamed {
+			if match(file.Path) {
+				filtered = append(filtered, file)
 			}
-		} else {
-			// New file - skip adding to old
-			fmt.Fprintf(os.Stderr, "  (new file)\n")
+			continue
 		}
 
-		// Get current version
-		content, err := os.ReadFile(file)
-		if err != nil {
-			tmpOld.Close()
-			tmpNew.Close()
-			os.Remove(tmpOldPath)
-			os.Remove(tmpNewPath)
-			return "", "", nil, fmt.Errorf("failed to read %s: %w", file, err)
-		}
-		
-		// Clean the content
-		cleanedNew := cleanYAMLContent(content)
-		if len(cleanedNew) > 0 {
-			// Add separator if not the first file
-			if i > 0 && tmpNew != nil {
-				tmpNew.WriteString("---\n")
-			}
-			if _, err := tmpNew.Write(cleanedNew); err != nil {
-				tmpOld.Close()
-				tmpNew.Close()
-				os.Remove(tmpOldPath)
-				os.Remove(tmpNewPath)
-				return "", "", nil, fmt.Errorf("failed to write new file content: %w", err)
-			}
-			// Ensure the file ends with a newline
-			if cleanedNew[len(cleanedNew)-1] != '\n' {
-				tmpNew.WriteString("\n")
-			}
+		oldSelected, newSelected := match(file.OldPath), match(file.Path)
+		switch {
+		case oldSelected && newSelected:
+			filtered = append(filtered, file)
+		case newSelected:
+			filtered = append(filtered, ChangedFile{Status: FileAdded, Path: file.Path})
+		case oldSelected:
+			filtered = append(filtered, ChangedFile{Status: FileDeleted, Path: file.OldPath})
 		}
 	}
+	return filtered
+}
 
-	tmpOld.Close()
-	tmpNew.Close()
+func isYAML(path string) bool {
+	return strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+}
 
-	cleanup = func() {
-		os.Remove(tmpOldPath)
-		os.Remove(tmpNewPath)
+// ResolveRange resolves the refs to compare. base may be given as "A...B" (compare
+// the merge base of A and B with B) or "A..B" (compare A with B), in which case head
+// must be empty. An empty head means the working tree.
+func ResolveRange(base, head string) (string, string, error) {
+	if from, to, found := strings.Cut(base, "..."); found {
+		if head != "" {
+			return "", "", fmt.Errorf("--head cannot be combined with a range in --base (%s)", base)
+		}
+		if to == "" {
+			to = "HEAD"
+		}
+		mergeBase, err := MergeBase(from, to)
+		if err != nil {
+			return "", "", err
+		}
+		base, head = mergeBase, to
+	} else if from, to, found := strings.Cut(base, ".."); found {
+		if head != "" {
+			return "", "", fmt.Errorf("--head cannot be combined with a range in --base (%s)", base)
+		}
+		if to == "" {
+			to = "HEAD"
+		}
+		base, head = from, to
 	}
 
-	return tmpOldPath, tmpNewPath, cleanup, nil
+	for _, ref := range []string{base, head} {
+		if ref != "" && !BranchExists(ref) {
+			return "", "", fmt.Errorf("unknown git ref: %s", ref)
+		}
+	}
+	return base, head, nil
 }
 
-// cleanYAMLContent removes leading comments and empty document separators from YAML content.
-// This prevents empty documents (which would be identified as __index__) from being included in the diff.
-func cleanYAMLContent(content []byte) []byte {
-	lines := bytes.Split(content, []byte("\n"))
-	var result [][]byte
-	skippingLeadingContent := true
-
-	for _, line := range lines {
-		trimmed := bytes.TrimSpace(line)
-		
-		if skippingLeadingContent {
-			// Skip leading comments
-			if bytes.HasPrefix(trimmed, []byte("#")) {
-				continue
-			}
-			// Skip leading empty lines
-			if len(trimmed) == 0 {
-				continue
-			}
-			// Skip leading document separators
-			if bytes.Equal(trimmed, []byte("---")) {
-				continue
-			}
-			// Found real content, stop skipping
-			skippingLeadingContent = false
+// MergeBase returns the best common ancestor of two refs
+func MergeBase(a, b string) (string, error) {
+	cmd := exec.Command("git", "merge-base", a, b)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("failed to find merge base of %s and %s: %w\nStderr: %s", a, b, err, string(exitErr.Stderr))
 		}
-		
-		// Add all content after we've found the first real line
-		result = append(result, line)
-	}
-	
-	if len(result) == 0 {
-		return []byte{}
+		return "", fmt.Errorf("failed to find merge base of %s and %s: %w", a, b, err)
 	}
-	
-	return bytes.Join(result, []byte("\n"))
+	return strings.TrimSpace(string(output)), nil
 }
 
-// ParseFilesWithSourceTracking parses multiple YAML files and tracks their source file paths.
-// This is used for --git-compare to maintain file source information for duplicate resource names.
-func ParseFilesWithSourceTracking(branch string, files []string) (oldDocs, newDocs []parser.Document, err error) {
+// ParseFilesBetween parses the versions of files at two refs, tracking their source file paths.
+// An empty head reads the files from the working tree. Files missing at base are new,
+// files missing at head are deleted, and files missing at both are an error.
+func ParseFilesBetween(base, head string, files []string) (oldDocs, newDocs []parser.Document, err error) {
+	var versions []fileVersion
 	for _, file := range files {
+		versions = append(versions,
+			fileVersion{ref: base, path: file, old: true},
+			fileVersion{ref: head, path: file})
+	}
+
+	contents, err := readFiles(versions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, file := range files {
 		fmt.Fprintf(os.Stderr, "Processing: %s\n", file)
-		
-		// Get old version from Git
-		cmd := exec.Command("git", "show", fmt.Sprintf("%s:%s", branch, file))
-		output, gitErr := cmd.Output()
-		if gitErr == nil {
-			// Parse old version
-			cleanedOld := cleanYAMLContent(output)
-			decoder := yaml.NewDecoder(bytes.NewReader(cleanedOld))
-			for {
-				var doc map[string]interface{}
-				if err := decoder.Decode(&doc); err != nil {
-					if err == io.EOF {
-						break
-					}
-					return nil, nil, fmt.Errorf("failed to parse old version of %s: %w", file, err)
-				}
-				raw, _ := yaml.Marshal(doc)
-				oldDocs = append(oldDocs, parser.Document{
-					Content:    doc,
-					Raw:        string(raw),
-					SourceFile: file,
-				})
+		if contents[2*i] == nil && contents[2*i+1] == nil {
+			where := "at " + head
+			if head == "" {
+				where = "in the working tree"
 			}
-		} else {
+			return nil, nil, fmt.Errorf("%s exists neither at %s nor %s", file, base, where)
+		}
+		if contents[2*i] == nil {
 			fmt.Fprintf(os.Stderr, "  (new file)\n")
 		}
-		
-		// Get current version
-		content, err := os.ReadFile(file)
+		if contents[2*i+1] == nil {
+			fmt.Fprintf(os.Stderr, "  (deleted file)\n")
+		}
+	}
+
+	return parseVersions(versions, contents)
+}
+
+// ParseChangedFiles parses the versions of changed files at two refs, tracking their
+// source file paths. An empty head reads the files from the working tree.
+// Deleted files only contribute old documents, and the old documents of renamed
+// files are read from their old path.
+func ParseChangedFiles(base, head string, files []ChangedFile) (oldDocs, newDocs []parser.Document, err error) {
+	var versions []fileVersion
+	for _, file := range files {
+		oldPath := file.Path
+		switch file.Status {
+		case FileRenamed:
+			oldPath = file.OldPath
+			fmt.Fprintf(os.Stderr, "Processing: %s (renamed from %s)\n", file.Path, file.OldPath)
+		case FileDeleted:
+			fmt.Fprintf(os.Stderr, "Processing: %s (deleted)\n", file.Path)
+		case FileAdded:
+			fmt.Fprintf(os.Stderr, "Processing: %s (new file)\n", file.Path)
+		default:
+			fmt.Fprintf(os.Stderr, "Processing: %s\n", file.Path)
+		}
+
+		if file.Status != FileAdded {
+			versions = append(versions, fileVersion{ref: base, path: oldPath, old: true})
+		}
+		if file.Status != FileDeleted {
+			versions = append(versions, fileVersion{ref: head, path: file.Path})
+		}
+	}
+
+	contents, err := readFiles(versions)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, v := range versions {
+		if contents[i] == nil {
+			return nil, nil, fmt.Errorf("failed to read %s at %s: file not found", v.path, v.ref)
+		}
+	}
+
+	return parseVersions(versions, contents)
+}
+
+// parseVersions parses the contents of file versions concurrently, skipping nil contents.
+// Documents keep the order of versions. Content is not cleaned so that line numbers
+// match the file.
+func parseVersions(versions []fileVersion, contents [][]byte) (oldDocs, newDocs []parser.Document, err error) {
+	docs := make([][]parser.Document, len(versions))
+	err = parallel(len(versions), func(i int) error {
+		if contents[i] == nil {
+			return nil
+		}
+		v := versions[i]
+		parsed, err := parser.ParseDocuments(contents[i], v.path)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to read %s: %w", file, err)
-		}
-		
-		cleanedNew := cleanYAMLContent(content)
-		decoder := yaml.NewDecoder(bytes.NewReader(cleanedNew))
-		for {
-			var doc map[string]interface{}
-			if err := decoder.Decode(&doc); err != nil {
-				if err == io.EOF {
-					break
-				}
-				return nil, nil, fmt.Errorf("failed to parse new version of %s: %w", file, err)
+			side := "new"
+			if v.old {
+				side = "old"
 			}
-			raw, _ := yaml.Marshal(doc)
-			newDocs = append(newDocs, parser.Document{
-				Content:    doc,
-				Raw:        string(raw),
-				SourceFile: file,
-			})
+			return fmt.Errorf("failed to parse %s version of %s: %w", side, v.path, err)
+		}
+		docs[i] = parsed
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, v := range versions {
+		if v.old {
+			oldDocs = append(oldDocs, docs[i]...)
+		} else {
+			newDocs = append(newDocs, docs[i]...)
 		}
 	}
-	
 	return oldDocs, newDocs, nil
 }
 
+// TopLevel returns the absolute path of the root of the current repository
+func TopLevel() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("failed to find the repository root: %w\nStderr: %s", err, string(exitErr.Stderr))
+		}
+		return "", fmt.Errorf("failed to find the repository root: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RepoPath returns path relative to the root of the current repository, with forward
+// slashes as used by Git
+func RepoPath(path string) (string, error) {
+	root, err := TopLevel()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	// The repository root is reported with symlinks resolved
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	} else if resolved, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		// The file may only exist at the compared refs
+		abs = filepath.Join(resolved, filepath.Base(abs))
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository %s", path, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
 // IsGitRepository checks if the current directory is inside a Git repository.
 func IsGitRepository() bool {
 	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
@@ -246,9 +348,9 @@ func IsGitRepository() bool {
 	return err == nil
 }
 
-// BranchExists checks if a branch exists in the repository.
+// BranchExists checks if a branch (or any ref resolving to a commit) exists in the repository.
 func BranchExists(branch string) bool {
-	cmd := exec.Command("git", "rev-parse", "--verify", branch)
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", branch+"^{commit}")
 	err := cmd.Run()
 	return err == nil
 }
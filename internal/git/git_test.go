@@ -0,0 +1,112 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testRepo creates a repository in a temporary directory, changes into it and
+// returns a function running git commands in it
+func testRepo(t *testing.T) func(args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	t.Chdir(t.TempDir())
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=yamlcmt", "GIT_AUTHOR_EMAIL=yamlcmt@example.com",
+			"GIT_COMMITTER_NAME=yamlcmt", "GIT_COMMITTER_EMAIL=yamlcmt@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	run("init", "--quiet")
+	return run
+}
+
+// writeFile writes content to path, creating its directory
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetChangedFiles(t *testing.T) {
+	run := testRepo(t)
+	writeFile(t, "k8s/café.yaml", "kind: ConfigMap\n")
+	writeFile(t, "k8s/old name.yaml", "kind: Service\nmetadata:\n  name: renamed\n")
+	writeFile(t, "k8s/modified.yaml", "kind: Deployment\n")
+	writeFile(t, "README.md", "readme\n")
+	run("add", ".")
+	run("commit", "--quiet", "-m", "base")
+
+	if err := os.Remove("k8s/café.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	run("mv", "k8s/old name.yaml", "k8s/new\tname.yaml")
+	writeFile(t, "k8s/modified.yaml", "kind: StatefulSet\n")
+	writeFile(t, "k8s/añadido.yml", "kind: Secret\n")
+	writeFile(t, "README.md", "changed\n")
+	run("add", "-A")
+
+	files, err := GetChangedFiles("HEAD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChangedFile{
+		{Status: FileAdded, Path: "k8s/añadido.yml"},
+		{Status: FileDeleted, Path: "k8s/café.yaml"},
+		{Status: FileModified, Path: "k8s/modified.yaml"},
+		{Status: FileRenamed, Path: "k8s/new\tname.yaml", OldPath: "k8s/old name.yaml"},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("GetChangedFiles() = %q, want %q", files, want)
+	}
+}
+
+func TestParseFilesBetween(t *testing.T) {
+	run := testRepo(t)
+	writeFile(t, "k8s/app.yaml", "kind: ConfigMap\nmetadata:\n  name: app\n")
+	run("add", ".")
+	run("commit", "--quiet", "-m", "base")
+
+	// Deleted in the working tree
+	if err := os.Remove("k8s/app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	oldDocs, newDocs, err := ParseFilesBetween("HEAD", "", []string{"k8s/app.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oldDocs) != 1 || len(newDocs) != 0 {
+		t.Errorf("got %d old and %d new documents of a file deleted in the working tree, want 1 and 0", len(oldDocs), len(newDocs))
+	}
+
+	// Deleted at head
+	run("commit", "--quiet", "-a", "-m", "delete")
+	oldDocs, newDocs, err = ParseFilesBetween("HEAD~1", "HEAD", []string{"k8s/app.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oldDocs) != 1 || len(newDocs) != 0 {
+		t.Errorf("got %d old and %d new documents of a file deleted at head, want 1 and 0", len(oldDocs), len(newDocs))
+	}
+
+	// Missing on both sides, e.g. a typo
+	for _, head := range []string{"", "HEAD"} {
+		if _, _, err := ParseFilesBetween("HEAD~1", head, []string{"k8s/ap.yaml"}); err == nil {
+			t.Errorf("ParseFilesBetween(%q) of a missing file succeeded, want an error", head)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"golang.org/x/oauth2"
+)
+
+// AppConfig holds the credentials used to authenticate as a GitHub App installation
+type AppConfig struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKeyFile string
+}
+
+// appTokenSource mints installation access tokens for a GitHub App.
+// It is wrapped in oauth2.ReuseTokenSource so a token is reused until it expires.
+type appTokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	client         *github.Client // authenticates with the app JWT
+}
+
+// newAppTokenSource creates a token source for the app installation.
+// base is the transport used for API requests, and configure applies the
+// Enterprise URLs of the client to the client used to mint tokens.
+func newAppTokenSource(cfg AppConfig, base http.RoundTripper, configure func(*github.Client) (*github.Client, error)) (oauth2.TokenSource, error) {
+	if cfg.InstallationID == 0 {
+		return nil, fmt.Errorf("GitHub App installation ID is not set")
+	}
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("GitHub App private key file is not set")
+	}
+
+	key, err := loadPrivateKey(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &appTokenSource{
+		appID:          cfg.AppID,
+		installationID: cfg.InstallationID,
+		key:            key,
+	}
+
+	client, err := configure(github.NewClient(&http.Client{
+		Transport: &jwtTransport{source: src, base: base},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	src.client = client
+
+	return oauth2.ReuseTokenSource(nil, src), nil
+}
+
+// Token mints a new installation access token
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, _, err := s.client.Apps.CreateInstallationToken(ctx, s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub App installation token: %w", classifyError(err))
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// jwt returns a JSON Web Token signed with the app's private key
+func (s *appTokenSource) jwt() (string, error) {
+	now := time.Now()
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	claims := map[string]interface{}{
+		"iat": now.Add(-60 * time.Second).Unix(), // allow for clock drift
+		"exp": now.Add(9 * time.Minute).Unix(),   // GitHub allows at most 10 minutes
+		"iss": fmt.Sprintf("%d", s.appID),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(headerJSON) + "." + encoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// jwtTransport authenticates requests with a freshly signed app JWT
+type jwtTransport struct {
+	source *appTokenSource
+	base   http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.jwt()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// loadPrivateKey reads an RSA private key in PKCS#1 or PKCS#8 PEM format
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,222 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/tyuhara/yamlcmt/internal/diff"
+	"github.com/tyuhara/yamlcmt/internal/parser"
+)
+
+// maxAnnotationsPerRequest is the number of annotations the Checks API accepts per request
+const maxAnnotationsPerRequest = 50
+
+// maxCheckRunSummary is the maximum length of a check run output summary
+const maxCheckRunSummary = 65535
+
+// Annotation levels of the Checks API
+const (
+	AnnotationNotice  = "notice"
+	AnnotationWarning = "warning"
+	AnnotationFailure = "failure"
+)
+
+// Annotation points to a line of a file changed in the check run's commit
+type Annotation struct {
+	Path    string
+	Line    int
+	Level   string
+	Title   string
+	Message string
+}
+
+// CheckRunOptions represents a completed check run to publish
+type CheckRunOptions struct {
+	Name        string
+	HeadSHA     string
+	Conclusion  string // success, neutral, action_required or failure
+	Title       string
+	Summary     string // Markdown
+	DetailsURL  string // Required by GitHub for action_required
+	Annotations []Annotation
+}
+
+// CreateCheckRun publishes a completed check run on a commit.
+// Annotations beyond the per-request limit are added with follow-up updates.
+func (c *Client) CreateCheckRun(repo string, opts CheckRunOptions) error {
+	ctx := context.Background()
+	client := c.gh
+
+	owner, repoName, err := parseRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	summary := opts.Summary
+	if len(summary) > maxCheckRunSummary {
+		summary = truncate(summary, maxCheckRunSummary-len(truncatedNotice)) + truncatedNotice
+	}
+
+	batches := annotationBatches(opts.Annotations)
+	output := func(annotations []*github.CheckRunAnnotation) *github.CheckRunOutput {
+		return &github.CheckRunOutput{
+			Title:       github.String(opts.Title),
+			Summary:     github.String(summary),
+			Annotations: annotations,
+		}
+	}
+
+	var first []*github.CheckRunAnnotation
+	if len(batches) > 0 {
+		first = batches[0]
+	}
+
+	var detailsURL *string
+	if opts.DetailsURL != "" {
+		detailsURL = github.String(opts.DetailsURL)
+	}
+
+	run, _, err := client.Checks.CreateCheckRun(ctx, owner, repoName, github.CreateCheckRunOptions{
+		Name:       opts.Name,
+		HeadSHA:    opts.HeadSHA,
+		DetailsURL: detailsURL,
+		Status:     github.String("completed"),
+		Conclusion: github.String(opts.Conclusion),
+		Output:     output(first),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create check run: %w", classifyError(err))
+	}
+
+	for i := 1; i < len(batches); i++ {
+		_, _, err := client.Checks.UpdateCheckRun(ctx, owner, repoName, run.GetID(), github.UpdateCheckRunOptions{
+			Name:   opts.Name,
+			Output: output(batches[i]),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add check run annotations: %w", classifyError(err))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Created GitHub check run: %s (%s, %d annotation(s))\n", opts.Name, opts.Conclusion, len(opts.Annotations))
+	return nil
+}
+
+const truncatedNotice = "\n\n… (truncated)"
+
+// annotationBatches splits annotations into batches accepted by the Checks API
+func annotationBatches(annotations []Annotation) [][]*github.CheckRunAnnotation {
+	var batches [][]*github.CheckRunAnnotation
+
+	for start := 0; start < len(annotations); start += maxAnnotationsPerRequest {
+		end := start + maxAnnotationsPerRequest
+		if end > len(annotations) {
+			end = len(annotations)
+		}
+
+		batch := make([]*github.CheckRunAnnotation, 0, end-start)
+		for _, a := range annotations[start:end] {
+			batch = append(batch, &github.CheckRunAnnotation{
+				Path:            github.String(a.Path),
+				StartLine:       github.Int(a.Line),
+				EndLine:         github.Int(a.Line),
+				AnnotationLevel: github.String(a.Level),
+				Title:           github.String(a.Title),
+				Message:         github.String(a.Message),
+			})
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// PathMapper converts the source file of a document to a slash-separated path relative
+// to the repository root, as check run annotations and review comments require.
+// sourceFile is empty when comparing two files, and old is set for the old side of
+// the comparison. An empty result means the file is not in the repository, or for the
+// old side, that the old document was not read from the base version of the file.
+type PathMapper func(sourceFile string, old bool) string
+
+// BuildAnnotations creates annotations for added and moved documents and field-level changes.
+// paths converts source files to repository paths; documents without one are skipped.
+// Changes without a known line and deleted documents are not annotated.
+func BuildAnnotations(result *diff.Result, paths PathMapper) []Annotation {
+	var annotations []Annotation
+
+	for _, key := range result.AddedKeys() {
+		doc := result.Added[key]
+		path := paths(doc.SourceFile, false)
+		if path == "" || doc.Node == nil {
+			continue
+		}
+		annotations = append(annotations, Annotation{
+			Path:    path,
+			Line:    doc.Node.Line,
+			Level:   AnnotationNotice,
+			Title:   "Added",
+			Message: fmt.Sprintf("Added %s", key),
+		})
+	}
+
+	for _, key := range result.ModifiedKeys() {
+		mod := result.Modified[key]
+		path := paths(mod.New.SourceFile, false)
+		if path == "" {
+			continue
+		}
+		for _, change := range mod.Changes {
+			if change.Line == 0 {
+				continue
+			}
+			level := AnnotationNotice
+			if change.Type == parser.ChangeDeleted {
+				level = AnnotationWarning
+			}
+			annotations = append(annotations, Annotation{
+				Path:    path,
+				Line:    change.Line,
+				Level:   level,
+				Title:   fmt.Sprintf("Modified %s", key),
+				Message: change.String(),
+			})
+		}
+	}
+
+	for _, key := range result.MovedKeys() {
+		moved := result.Moved[key]
+		path := paths(moved.New.SourceFile, false)
+		if path == "" {
+			continue
+		}
+		if moved.New.Node != nil {
+			annotations = append(annotations, Annotation{
+				Path:    path,
+				Line:    moved.New.Node.Line,
+				Level:   AnnotationNotice,
+				Title:   "Moved",
+				Message: fmt.Sprintf("Moved %s from %s", key, moved.Old.SourceFile),
+			})
+		}
+		for _, change := range moved.Changes {
+			if change.Line == 0 {
+				continue
+			}
+			level := AnnotationNotice
+			if change.Type == parser.ChangeDeleted {
+				level = AnnotationWarning
+			}
+			annotations = append(annotations, Annotation{
+				Path:    path,
+				Line:    change.Line,
+				Level:   level,
+				Title:   fmt.Sprintf("Moved %s", key),
+				Message: change.String(),
+			})
+		}
+	}
+
+	return annotations
+}
@@ -0,0 +1,106 @@
+package github
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/tyuhara/yamlcmt/internal/httputil"
+	"golang.org/x/oauth2"
+)
+
+// ClientConfig holds the settings used to connect to the GitHub API
+type ClientConfig struct {
+	// Token is a personal access token or GITHUB_TOKEN.
+	// It is not needed when App is set.
+	Token string
+
+	// App authenticates as a GitHub App installation instead of using Token
+	App *AppConfig
+
+	// BaseURL and UploadURL point the client to a GitHub Enterprise Server instance
+	// (e.g. https://ghe.example.com/api/v3/). api.github.com is used if BaseURL is empty.
+	BaseURL   string
+	UploadURL string
+
+	// CACertFile is a PEM bundle trusted in addition to the system certificates
+	CACertFile string
+
+	// Timeout limits each attempt of an API request (DefaultTimeout if 0)
+	Timeout time.Duration
+
+	// MaxRetries is the number of retries of requests that failed with a
+	// transient error (DefaultMaxRetries if 0, no retries if negative)
+	MaxRetries int
+}
+
+// Client performs yamlcmt's operations against the GitHub API
+type Client struct {
+	gh *github.Client
+}
+
+// NewClient creates a GitHub client from the given configuration
+func NewClient(cfg ClientConfig) (*Client, error) {
+	if cfg.Token == "" && cfg.App == nil {
+		return nil, fmt.Errorf("neither a GitHub token nor GitHub App credentials are set")
+	}
+
+	var base http.RoundTripper = http.DefaultTransport
+	if cfg.CACertFile != "" {
+		transport, err := httputil.TransportWithCA(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		base = transport
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	maxRetries := cfg.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	base = &retryTransport{base: base, timeout: timeout, maxRetries: maxRetries}
+
+	// withURLs points a client at the configured Enterprise Server, if any
+	withURLs := func(client *github.Client) (*github.Client, error) {
+		if cfg.BaseURL == "" {
+			return client, nil
+		}
+		uploadURL := cfg.UploadURL
+		if uploadURL == "" {
+			uploadURL = cfg.BaseURL
+		}
+		client, err := client.WithEnterpriseURLs(cfg.BaseURL, uploadURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub Enterprise URL: %w", err)
+		}
+		return client, nil
+	}
+
+	var ts oauth2.TokenSource
+	if cfg.App != nil {
+		var err error
+		ts, err = newAppTokenSource(*cfg.App, base, withURLs)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ts = oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: cfg.Token},
+		)
+	}
+
+	httpClient := &http.Client{
+		Transport: &oauth2.Transport{Source: ts, Base: base},
+	}
+	client, err := withURLs(github.NewClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{gh: client}, nil
+}
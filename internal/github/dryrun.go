@@ -0,0 +1,56 @@
+package github
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/tyuhara/yamlcmt/internal/vcs"
+)
+
+// Publisher adds the GitHub-only features to vcs.Provider.
+// It is implemented by Client and, for --dry-run, by DryRun.
+type Publisher interface {
+	vcs.Provider
+	CreateCheckRun(repo string, opts CheckRunOptions) error
+	CreateReview(repo string, prNumber int, body string, comments []ReviewComment) error
+}
+
+var (
+	_ Publisher = (*Client)(nil)
+	_ Publisher = (*DryRun)(nil)
+)
+
+// DryRun prints the actions a Client would perform instead of calling the GitHub API.
+// It needs no credentials, so it cannot see the current state of the pull request.
+type DryRun struct {
+	*vcs.DryRun
+}
+
+// NewDryRun creates a DryRun that prints to w
+func NewDryRun(w io.Writer) *DryRun {
+	return &DryRun{vcs.NewDryRun(w, vcs.GitHub)}
+}
+
+// CreateCheckRun prints the check run that would be created
+func (d *DryRun) CreateCheckRun(repo string, opts CheckRunOptions) error {
+	d.Printf("Would create check run %q on %s@%s: %s\n", opts.Name, repo, opts.HeadSHA, opts.Conclusion)
+	d.Writef("  title: %s\n", opts.Title)
+	if opts.DetailsURL != "" {
+		d.Writef("  details: %s\n", opts.DetailsURL)
+	}
+	for _, a := range opts.Annotations {
+		d.Writef("  %s:%d %s %s: %s\n", a.Path, a.Line, a.Level, a.Title, a.Message)
+	}
+	d.Section("check run summary", opts.Summary)
+	return nil
+}
+
+// CreateReview prints the review comments that would be posted.
+// Comments outside the pull request diff, which a Client skips, are included.
+func (d *DryRun) CreateReview(repo string, prNumber int, body string, comments []ReviewComment) error {
+	d.Printf("Would post a review with up to %d comment(s) on %s: %s\n", len(comments), d.Ref(repo, prNumber), body)
+	for _, comment := range comments {
+		d.Section(fmt.Sprintf("%s:%d (%s)", comment.Path, comment.Line, comment.Side), comment.Body+"\n")
+	}
+	return nil
+}
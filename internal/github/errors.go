@@ -0,0 +1,87 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/google/go-github/v66/github"
+)
+
+// ErrorKind classifies failed GitHub API calls by what the user can do about them
+type ErrorKind string
+
+const (
+	ErrorAuth       ErrorKind = "authentication failed"
+	ErrorPermission ErrorKind = "permission denied"
+	ErrorNotFound   ErrorKind = "not found"
+	ErrorTransient  ErrorKind = "transient error"
+)
+
+// errorHints explain the usual cause of each kind of error
+var errorHints = map[ErrorKind]string{
+	ErrorAuth:       "check that the token or GitHub App credentials are valid and not expired",
+	ErrorPermission: "check the token's scopes or the permissions granted to the workflow or GitHub App",
+	ErrorNotFound:   "check the repository and PR number; private repositories also return 404 without read access",
+	ErrorTransient:  "GitHub did not respond successfully after retrying; try again later",
+}
+
+// APIError is a GitHub API error annotated with its kind
+type APIError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %v (%s)", e.Kind, e.Err, errorHints[e.Kind])
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError wraps err in an APIError if its kind can be determined
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return err
+	}
+	if kind, ok := errorKind(err); ok {
+		return &APIError{Kind: kind, Err: err}
+	}
+	return err
+}
+
+func errorKind(err error) (ErrorKind, bool) {
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return ErrorTransient, true
+	}
+
+	var respErr *github.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		switch code := respErr.Response.StatusCode; {
+		case code == http.StatusUnauthorized:
+			return ErrorAuth, true
+		case code == http.StatusForbidden:
+			return ErrorPermission, true
+		case code == http.StatusNotFound:
+			return ErrorNotFound, true
+		case code == http.StatusTooManyRequests || retryableStatus(code):
+			return ErrorTransient, true
+		}
+		return "", false
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorTransient, true
+	}
+	return "", false
+}
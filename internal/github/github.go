@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -11,7 +12,7 @@ import (
 
 	"github.com/google/go-github/v66/github"
 	"github.com/tyuhara/yamlcmt/internal/diff"
-	"golang.org/x/oauth2"
+	"github.com/tyuhara/yamlcmt/internal/vcs"
 )
 
 // TemplateData represents data available in templates
@@ -22,27 +23,15 @@ type TemplateData struct {
 	Added        int
 	Deleted      int
 	Modified     int
+	Moved        int
 	AddedList    []string
 	DeletedList  []string
 	ModifiedList []string
+	MovedList    []string
 	Link         string
 	Vars         map[string]interface{}
 }
 
-// getClient creates a GitHub client with the token from environment variable
-func getClient(ctx context.Context) (*github.Client, error) {
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		return nil, fmt.Errorf("GITHUB_TOKEN environment variable is not set")
-	}
-
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	return github.NewClient(tc), nil
-}
-
 // parseRepo splits "owner/repo" into owner and repo
 func parseRepo(repo string) (string, string, error) {
 	parts := strings.Split(repo, "/")
@@ -52,93 +41,258 @@ func parseRepo(repo string) (string, string, error) {
 	return parts[0], parts[1], nil
 }
 
-// PostComment posts a comment to a GitHub PR using go-github
-func PostComment(repo string, prNumber int, body string) error {
+// UpsertComment posts a comment embedding the marker for target, handling
+// comments from previous runs with the same marker according to mode.
+// Several bodies are posted as consecutive parts, each linking to the previous one.
+func (c *Client) UpsertComment(repo string, prNumber int, bodies []string, mode string, target string) error {
 	ctx := context.Background()
-	client, err := getClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
-	}
+	client := c.gh
 
 	owner, repoName, err := parseRepo(repo)
 	if err != nil {
 		return err
 	}
 
-	comment := &github.IssueComment{
-		Body: github.String(body),
+	marker := vcs.CommentMarker(target)
+
+	var previous []*github.IssueComment
+	if mode != "" && mode != vcs.CommentModeCreate {
+		previous, err = findComments(ctx, client, owner, repoName, prNumber, marker)
+		if err != nil {
+			return err
+		}
 	}
 
-	_, _, err = client.Issues.CreateComment(ctx, owner, repoName, prNumber, comment)
-	if err != nil {
-		return fmt.Errorf("failed to post comment: %w", err)
+	switch mode {
+	case "", vcs.CommentModeCreate:
+		previous = nil
+	case vcs.CommentModeUpdate:
+		// The most recent comments are edited in place below; older parts of a
+		// comment previously split into more parts are deleted
+		if len(previous) > len(bodies) {
+			stale := previous[:len(previous)-len(bodies)]
+			previous = previous[len(previous)-len(bodies):]
+			for _, prev := range stale {
+				if !vcs.IsPart(prev.GetBody()) {
+					continue
+				}
+				if _, err := client.Issues.DeleteComment(ctx, owner, repoName, prev.GetID()); err != nil {
+					return fmt.Errorf("failed to delete comment %d: %w", prev.GetID(), classifyError(err))
+				}
+			}
+		}
+	case vcs.CommentModeMinimize:
+		for _, prev := range previous {
+			if err := minimizeComment(ctx, client, prev.GetNodeID()); err != nil {
+				return fmt.Errorf("failed to minimize comment %d: %w", prev.GetID(), classifyError(err))
+			}
+		}
+		if len(previous) > 0 {
+			fmt.Fprintf(os.Stderr, "✓ Minimized %d previous GitHub comment(s)\n", len(previous))
+		}
+		previous = nil
+	case vcs.CommentModeDelete:
+		for _, prev := range previous {
+			if _, err := client.Issues.DeleteComment(ctx, owner, repoName, prev.GetID()); err != nil {
+				return fmt.Errorf("failed to delete comment %d: %w", prev.GetID(), classifyError(err))
+			}
+		}
+		if len(previous) > 0 {
+			fmt.Fprintf(os.Stderr, "✓ Deleted %d previous GitHub comment(s)\n", len(previous))
+		}
+		previous = nil
+	default:
+		return vcs.ValidateCommentMode(mode)
+	}
+
+	// In update mode, previous comments are reused in order; the rest are created
+	prevURL := ""
+	for i, body := range bodies {
+		if len(bodies) > 1 {
+			body = vcs.PartHeader(i, len(bodies), prevURL) + body
+		}
+		comment := &github.IssueComment{
+			Body: github.String(body + "\n" + marker + "\n"),
+		}
+
+		if i < len(previous) {
+			edited, _, err := client.Issues.EditComment(ctx, owner, repoName, previous[i].GetID(), comment)
+			if err != nil {
+				return fmt.Errorf("failed to update comment: %w", classifyError(err))
+			}
+			fmt.Fprintf(os.Stderr, "✓ Updated GitHub comment: %s\n", edited.GetHTMLURL())
+			prevURL = edited.GetHTMLURL()
+			continue
+		}
+
+		created, _, err := client.Issues.CreateComment(ctx, owner, repoName, prNumber, comment)
+		if err != nil {
+			return fmt.Errorf("failed to post comment: %w", classifyError(err))
+		}
+		fmt.Fprintf(os.Stderr, "✓ Posted GitHub comment\n")
+		prevURL = created.GetHTMLURL()
 	}
 
-	fmt.Fprintf(os.Stderr, "✓ Posted GitHub comment\n")
 	return nil
 }
 
-// AddLabel adds a label to a GitHub PR using go-github
-func AddLabel(repo string, prNumber int, label string) error {
-	if label == "" {
-		return nil
+// findComments returns the PR comments containing marker, oldest first
+func findComments(ctx context.Context, client *github.Client, owner, repoName string, prNumber int, marker string) ([]*github.IssueComment, error) {
+	var found []*github.IssueComment
+
+	opts := &github.IssueListCommentsOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		comments, resp, err := client.Issues.ListComments(ctx, owner, repoName, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list comments: %w", classifyError(err))
+		}
+		for _, c := range comments {
+			if strings.Contains(c.GetBody(), marker) {
+				found = append(found, c)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
-	ctx := context.Background()
-	client, err := getClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
+	return found, nil
+}
+
+// minimizeComment hides a comment as outdated using the GraphQL API,
+// which is the only API that supports minimizing comments
+func minimizeComment(ctx context.Context, client *github.Client, nodeID string) error {
+	query := map[string]interface{}{
+		"query": `mutation($id: ID!) { minimizeComment(input: {subjectId: $id, classifier: OUTDATED}) { clientMutationId } }`,
+		"variables": map[string]interface{}{
+			"id": nodeID,
+		},
 	}
 
-	owner, repoName, err := parseRepo(repo)
+	req, err := client.NewRequest("POST", graphqlURL(client), query)
 	if err != nil {
 		return err
 	}
 
-	_, _, err = client.Issues.AddLabelsToIssue(ctx, owner, repoName, prNumber, []string{label})
-	if err != nil {
-		return fmt.Errorf("failed to add label: %w", err)
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := client.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", resp.Errors[0].Message)
 	}
 
-	fmt.Fprintf(os.Stderr, "✓ Applied GitHub label: %s\n", label)
 	return nil
 }
 
-// AddLabels adds multiple labels to a GitHub PR using go-github
-func AddLabels(repo string, prNumber int, labels []string) error {
-	if len(labels) == 0 {
-		return nil
+// graphqlURL returns the GraphQL endpoint for the client's REST API base URL
+func graphqlURL(client *github.Client) string {
+	base := *client.BaseURL
+	if strings.HasSuffix(base.Path, "/api/v3/") {
+		// GitHub Enterprise Server
+		base.Path = strings.TrimSuffix(base.Path, "/v3/") + "/graphql"
+		return base.String()
 	}
+	base.Path = "/graphql"
+	return base.String()
+}
+
+// ReconcileLabels makes the labels managed by yamlcmt on a GitHub PR match labels.
+// Labels in labels that are missing are added, and labels in managed that are
+// present but not in labels are removed. Other labels on the PR are left untouched.
+func (c *Client) ReconcileLabels(repo string, prNumber int, labels []string, managed []string) error {
+	ctx := context.Background()
+	client := c.gh
 
-	// Filter out empty labels
-	validLabels := make([]string, 0, len(labels))
+	owner, repoName, err := parseRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		existing, resp, err := client.Issues.ListLabelsByIssue(ctx, owner, repoName, prNumber, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list labels: %w", classifyError(err))
+		}
+		for _, label := range existing {
+			current[label.GetName()] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	// Filter out empty labels and labels that are already applied
+	desired := make(map[string]bool, len(labels))
+	var toAdd []string
 	for _, label := range labels {
-		if label != "" {
-			validLabels = append(validLabels, label)
+		if label == "" || desired[label] {
+			continue
+		}
+		desired[label] = true
+		if !current[label] {
+			toAdd = append(toAdd, label)
 		}
 	}
 
-	if len(validLabels) == 0 {
-		return nil
+	var removed []string
+	for _, label := range managed {
+		if label == "" || desired[label] || !current[label] {
+			continue
+		}
+		// go-github does not escape the label name, and managed labels contain slashes
+		if _, err := client.Issues.RemoveLabelForIssue(ctx, owner, repoName, prNumber, url.PathEscape(label)); err != nil {
+			return fmt.Errorf("failed to remove label %s: %w", label, classifyError(err))
+		}
+		current[label] = false
+		removed = append(removed, label)
+	}
+	if len(removed) > 0 {
+		fmt.Fprintf(os.Stderr, "✓ Removed GitHub labels: %s\n", strings.Join(removed, ", "))
 	}
 
-	ctx := context.Background()
-	client, err := getClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create GitHub client: %w", err)
+	if len(toAdd) > 0 {
+		if _, _, err := client.Issues.AddLabelsToIssue(ctx, owner, repoName, prNumber, toAdd); err != nil {
+			return fmt.Errorf("failed to add labels: %w", classifyError(err))
+		}
+		fmt.Fprintf(os.Stderr, "✓ Applied GitHub labels: %s\n", strings.Join(toAdd, ", "))
 	}
 
-	owner, repoName, err := parseRepo(repo)
-	if err != nil {
-		return err
+	return nil
+}
+
+// AppendStepSummary appends markdown to the GitHub Actions job summary file
+// referenced by the GITHUB_STEP_SUMMARY environment variable
+func AppendStepSummary(body string) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return fmt.Errorf("GITHUB_STEP_SUMMARY environment variable is not set (not running in GitHub Actions?)")
 	}
 
-	_, _, err = client.Issues.AddLabelsToIssue(ctx, owner, repoName, prNumber, validLabels)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 	if err != nil {
-		return fmt.Errorf("failed to add labels: %w", err)
+		return fmt.Errorf("failed to open step summary file: %w", err)
+	}
+	defer f.Close()
+
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	if _, err := f.WriteString(body); err != nil {
+		return fmt.Errorf("failed to write step summary: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "✓ Applied GitHub labels: %s\n", strings.Join(validLabels, ", "))
+	fmt.Fprintf(os.Stderr, "✓ Wrote GitHub Actions job summary\n")
 	return nil
 }
 
@@ -162,8 +316,12 @@ func PrepareTemplateData(result *diff.Result, details string, link string, vars
 	added := len(result.Added)
 	deleted := len(result.Deleted)
 	modified := len(result.Modified)
+	moved := len(result.Moved)
 
 	summary := fmt.Sprintf("Plan: %d to add, %d to delete, %d to modify", added, deleted, modified)
+	if moved > 0 {
+		summary += fmt.Sprintf(", %d to move", moved)
+	}
 
 	// Extract and sort keys
 	addedList := make([]string, 0, len(result.Added))
@@ -184,6 +342,8 @@ func PrepareTemplateData(result *diff.Result, details string, link string, vars
 	}
 	sort.Strings(modifiedList)
 
+	movedList := result.MovedKeys()
+
 	return TemplateData{
 		Summary:      summary,
 		Details:      details,
@@ -191,9 +351,11 @@ func PrepareTemplateData(result *diff.Result, details string, link string, vars
 		Added:        added,
 		Deleted:      deleted,
 		Modified:     modified,
+		Moved:        moved,
 		AddedList:    addedList,
 		DeletedList:  deletedList,
 		ModifiedList: modifiedList,
+		MovedList:    movedList,
 		Link:         link,
 		Vars:         vars,
 	}
@@ -0,0 +1,50 @@
+package github
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestReconcileLabelsEscapesRemovedLabel(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[{"name":"config-sync/destroy"},{"name":"other"}]`))
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.EscapedPath())
+			w.Write([]byte(`[]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientConfig{Token: "token", BaseURL: server.URL + "/", MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stderr = devNull
+	defer func() { os.Stderr = stderr }()
+
+	err = client.ReconcileLabels("owner/repo", 1, nil, []string{"config-sync/destroy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "/api/v3/repos/owner/repo/issues/1/labels/config-sync%2Fdestroy"
+	if len(deleted) != 1 || deleted[0] != want {
+		t.Errorf("DELETE paths = %q, want [%q]", deleted, want)
+	}
+}
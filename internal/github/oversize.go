@@ -0,0 +1,174 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxCommentLength is the maximum length of a GitHub comment body
+const MaxCommentLength = 65536
+
+// commentReserve is left free in each comment for the marker and the part header
+const commentReserve = 512
+
+// maxSplitComments limits the number of comments posted by the split strategy
+const maxSplitComments = 10
+
+// Strategies for comments exceeding MaxCommentLength
+const (
+	OversizeTruncate = "truncate" // Cut .Details and note how many changes were omitted
+	OversizeSplit    = "split"    // Spread .Details over several linked comments
+	OversizeSummary  = "summary"  // Render a summary-only template instead
+)
+
+// changeLinePattern matches the lines of .Details that start a change
+// (added, deleted and moved documents and field-level changes of the fields format)
+var changeLinePattern = regexp.MustCompile(`^(\+ Added:|- Deleted:|→ Moved:|  [+~-] )`)
+
+// ValidateOversizeStrategy returns an error for unknown oversize strategies ("" is truncate)
+func ValidateOversizeStrategy(strategy string) error {
+	switch strategy {
+	case "", OversizeTruncate, OversizeSplit, OversizeSummary:
+		return nil
+	}
+	return fmt.Errorf("unknown oversize strategy: %s (expected: truncate, split or summary)", strategy)
+}
+
+// RenderComments renders the comment template into bodies that fit GitHub's size limit.
+// A single body is returned unless the template is too large and strategy is split.
+// summaryTmpl is used by the summary strategy; it defaults to tmpl without .Details.
+func RenderComments(tmpl string, data TemplateData, strategy string, summaryTmpl string) ([]string, error) {
+	// Checked before the size so that a typo does not only fail on large comments
+	if err := ValidateOversizeStrategy(strategy); err != nil {
+		return nil, err
+	}
+	budget := MaxCommentLength - commentReserve
+
+	body, err := RenderTemplate(tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) <= budget {
+		return []string{body}, nil
+	}
+
+	var bodies []string
+	switch strategy {
+	case "", OversizeTruncate:
+		bodies, err = renderTruncated(tmpl, data, budget, 1)
+	case OversizeSplit:
+		bodies, err = renderTruncated(tmpl, data, budget, maxSplitComments)
+	case OversizeSummary:
+		summaryData := data
+		summaryData.Details = ""
+		if summaryTmpl == "" {
+			summaryTmpl = tmpl
+		}
+		body, err = RenderTemplate(summaryTmpl, summaryData)
+		bodies = []string{body}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The template itself may exceed the limit regardless of .Details
+	for i, body := range bodies {
+		if len(body) > budget {
+			bodies[i] = truncate(body, budget-len(truncatedNotice)) + truncatedNotice
+		}
+	}
+	return bodies, nil
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// renderTruncated spreads the lines of .Details over at most maxParts bodies.
+// Lines that do not fit are omitted with a notice at the end of the last body.
+func renderTruncated(tmpl string, data TemplateData, budget, maxParts int) ([]string, error) {
+	empty := data
+	empty.Details = ""
+	overhead, err := RenderTemplate(tmpl, empty)
+	if err != nil {
+		return nil, err
+	}
+
+	// Room for .Details in each body, keeping space for the omission notice
+	room := budget - len(overhead) - 64
+	if room <= 0 {
+		room = 0
+	}
+
+	lines := strings.Split(strings.TrimRight(data.Details, "\n"), "\n")
+
+	var parts []string
+	for start := 0; start < len(lines) && len(parts) < maxParts; {
+		size, end := 0, start
+		for end < len(lines) && size+len(lines[end])+1 <= room {
+			size += len(lines[end]) + 1
+			end++
+		}
+		if end == start {
+			// A single line longer than the room; cut it rather than loop forever
+			if room == 0 {
+				break
+			}
+			lines[start] = truncate(lines[start], room-1)
+			continue
+		}
+		parts = append(parts, strings.Join(lines[start:end], "\n"))
+		start = end
+	}
+
+	kept := 0
+	for _, part := range parts {
+		kept += strings.Count(part, "\n") + 1
+	}
+	if omitted := omittedNotice(lines[kept:]); omitted != "" {
+		if len(parts) == 0 {
+			parts = append(parts, omitted)
+		} else {
+			parts[len(parts)-1] += "\n" + omitted
+		}
+	}
+
+	bodies := make([]string, 0, len(parts))
+	for _, part := range parts {
+		partData := data
+		partData.Details = part
+		body, err := RenderTemplate(tmpl, partData)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
+
+// omittedNotice describes omitted lines of .Details, counting changes where possible
+func omittedNotice(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	changes := 0
+	for _, line := range lines {
+		if changeLinePattern.MatchString(line) {
+			changes++
+		}
+	}
+	if changes > 0 {
+		return fmt.Sprintf("… %d more change(s) omitted", changes)
+	}
+	return fmt.Sprintf("… %d more line(s) omitted", len(lines))
+}
@@ -0,0 +1,194 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tyuhara/yamlcmt/internal/httputil"
+)
+
+// Defaults for ClientConfig.Timeout and ClientConfig.MaxRetries
+const (
+	DefaultTimeout    = 30 * time.Second
+	DefaultMaxRetries = 3
+)
+
+// Bounds of the delay between retries
+const (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 30 * time.Second
+
+	// rateLimitBackoff is the shortest wait after a rate limit without a reset time,
+	// as GitHub asks to wait at least a minute after a secondary rate limit
+	rateLimitBackoff = 1 * time.Minute
+
+	// maxRetryWait is the longest total wait across retries before giving up
+	maxRetryWait = 2 * time.Minute
+)
+
+// retryTransport retries requests that failed with a transient error, waiting
+// with exponential backoff or as long as GitHub asks for rate limits.
+// Each attempt is limited by timeout.
+type retryTransport struct {
+	base       http.RoundTripper
+	timeout    time.Duration
+	maxRetries int
+
+	// sleep waits between attempts (sleepContext if nil), replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		resp, err := t.roundTrip(req)
+
+		if attempt >= t.maxRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := retryDelay(resp, attempt)
+		if waited+wait > maxRetryWait {
+			return resp, err
+		}
+		waited += wait
+		if resp != nil {
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		reason := "network error"
+		if resp != nil {
+			reason = resp.Status
+		}
+		fmt.Fprintf(os.Stderr, "GitHub API %s %s: %s, retrying in %s (%d/%d)\n", req.Method, req.URL.Path, reason, wait.Round(time.Second), attempt+1, t.maxRetries)
+
+		sleep := t.sleep
+		if sleep == nil {
+			sleep = sleepContext
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry %s %s: request body cannot be replayed", req.Method, req.URL.Path)
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// sleepContext waits for d, or returns early with the error of a done ctx
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// roundTrip performs a single attempt, limited by the timeout
+func (t *retryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout also covers reading the body, so cancel only once it is closed
+	resp.Body = httputil.CancelOnClose(resp.Body, cancel)
+	return resp, nil
+}
+
+// retryable reports whether a request failed with a transient error and can be sent again.
+// Requests that are not idempotent (POST, PATCH) may already have been processed after a
+// network error or a gateway error, so they are only retried when rate limited.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// Not when the caller gave up
+		return idempotent(req.Method) && req.Context().Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	if rateLimited(resp) {
+		return true
+	}
+	return idempotent(req.Method) && retryableStatus(resp.StatusCode)
+}
+
+// rateLimited reports whether GitHub rejected a request without processing it because of a rate limit
+func rateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	// Secondary rate limits and exhausted primary rate limits are reported as 403
+	return resp.StatusCode == http.StatusForbidden &&
+		(resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0")
+}
+
+// idempotent reports whether sending a request with the method twice has the same effect as once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryableStatus reports whether a status code indicates a transient error
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before the next attempt, following
+// Retry-After and X-RateLimit-Reset when GitHub sends them. Rate limits without
+// either header wait at least rateLimitBackoff.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+				if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+					return wait + time.Second // allow for clock drift
+				}
+			}
+		}
+	}
+
+	backoff := initialBackoff << attempt
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	if resp != nil && rateLimited(resp) && backoff < rateLimitBackoff {
+		backoff = rateLimitBackoff
+	}
+	// Up to 25% jitter so concurrent jobs do not retry in lockstep
+	return backoff + time.Duration(rand.Int63n(int64(backoff/4)+1))
+}
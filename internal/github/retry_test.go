@@ -0,0 +1,224 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// response is a status and headers returned by a test server
+type response struct {
+	status int
+	header map[string]string
+}
+
+// retryServer returns the responses in order, repeating the last one, and records
+// the bodies of the requests it receives
+func retryServer(t *testing.T, responses ...response) (*httptest.Server, *[]string) {
+	t.Helper()
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		resp := responses[min(len(bodies), len(responses))-1]
+		for k, v := range resp.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+// newTestTransport returns a retryTransport recording its waits instead of sleeping
+func newTestTransport(base http.RoundTripper) (*retryTransport, *[]time.Duration) {
+	var waits []time.Duration
+	return &retryTransport{
+		base:       base,
+		maxRetries: DefaultMaxRetries,
+		sleep: func(ctx context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		},
+	}, &waits
+}
+
+// quietRetries hides the retry messages printed to stderr
+func quietRetries(t *testing.T) {
+	t.Helper()
+	stderr := os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = devNull
+	t.Cleanup(func() {
+		os.Stderr = stderr
+		devNull.Close()
+	})
+}
+
+func TestRetryTransportWaits(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10)
+	tests := []struct {
+		name     string
+		first    response
+		min, max time.Duration
+	}{
+		{
+			name:  "Retry-After",
+			first: response{http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}},
+			min:   5 * time.Second,
+			max:   5 * time.Second,
+		},
+		{
+			name:  "X-RateLimit-Reset",
+			first: response{http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}},
+			min:   29 * time.Second,
+			max:   32 * time.Second,
+		},
+		{
+			name:  "rate limit without a reset time",
+			first: response{http.StatusTooManyRequests, nil},
+			min:   rateLimitBackoff,
+			max:   rateLimitBackoff * 5 / 4,
+		},
+		{
+			name:  "gateway error",
+			first: response{http.StatusBadGateway, nil},
+			min:   initialBackoff,
+			max:   initialBackoff * 5 / 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quietRetries(t)
+			server, bodies := retryServer(t, tt.first, response{status: http.StatusOK})
+			transport, waits := newTestTransport(http.DefaultTransport)
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK || len(*bodies) != 2 {
+				t.Fatalf("got status %d after %d requests, want 200 after 2", resp.StatusCode, len(*bodies))
+			}
+			if len(*waits) != 1 || (*waits)[0] < tt.min || (*waits)[0] > tt.max {
+				t.Errorf("waits = %v, want one between %s and %s", *waits, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetryTransportMaxRetryWait(t *testing.T) {
+	quietRetries(t)
+
+	// 50s and 50s fit in the two minute total, a third 50s does not
+	server, bodies := retryServer(t, response{http.StatusTooManyRequests, map[string]string{"Retry-After": "50"}})
+	transport, waits := newTestTransport(http.DefaultTransport)
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || len(*bodies) != 3 || len(*waits) != 2 {
+		t.Errorf("got status %d after %d requests and waits %v, want 429 after 3 requests and 2 waits", resp.StatusCode, len(*bodies), *waits)
+	}
+
+	// A single wait longer than the total is not attempted
+	server, bodies = retryServer(t, response{http.StatusTooManyRequests, map[string]string{"Retry-After": "121"}})
+	transport, waits = newTestTransport(http.DefaultTransport)
+	resp, err = (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(*bodies) != 1 || len(*waits) != 0 {
+		t.Errorf("got %d requests and waits %v, want 1 request and no waits", len(*bodies), *waits)
+	}
+}
+
+func TestRetryTransportIdempotency(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		first    response
+		requests int
+	}{
+		{"GET on gateway error", http.MethodGet, response{status: http.StatusBadGateway}, 2},
+		{"DELETE on gateway error", http.MethodDelete, response{status: http.StatusServiceUnavailable}, 2},
+		{"POST on gateway error", http.MethodPost, response{status: http.StatusBadGateway}, 1},
+		{"PATCH on gateway error", http.MethodPatch, response{status: http.StatusGatewayTimeout}, 1},
+		{"POST when rate limited", http.MethodPost, response{http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}}, 2},
+		{"POST on other errors", http.MethodPost, response{status: http.StatusInternalServerError}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quietRetries(t)
+			server, bodies := retryServer(t, tt.first, response{status: http.StatusOK})
+			transport, _ := newTestTransport(http.DefaultTransport)
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(`{"body":"comment"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := (&http.Client{Transport: transport}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if len(*bodies) != tt.requests {
+				t.Fatalf("got %d requests, want %d", len(*bodies), tt.requests)
+			}
+			// Retried requests send the body again
+			for i, body := range *bodies {
+				if body != `{"body":"comment"}` {
+					t.Errorf("body of request %d = %q", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransportNetworkError(t *testing.T) {
+	for method, want := range map[string]int{http.MethodGet: 1 + DefaultMaxRetries, http.MethodPost: 1} {
+		t.Run(method, func(t *testing.T) {
+			quietRetries(t)
+			attempts := 0
+			transport, _ := newTestTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				attempts++
+				return nil, errors.New("connection reset")
+			}))
+
+			req, err := http.NewRequest(method, "http://example.com/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := transport.RoundTrip(req); err == nil {
+				t.Fatal("RoundTrip succeeded, want an error")
+			}
+			if attempts != want {
+				t.Errorf("got %d attempts, want %d", attempts, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/tyuhara/yamlcmt/internal/diff"
+	"github.com/tyuhara/yamlcmt/internal/parser"
+)
+
+// Sides of a pull request diff that a review comment can be placed on
+const (
+	SideLeft  = "LEFT"  // Old version, for deleted lines
+	SideRight = "RIGHT" // New version
+)
+
+// ReviewRule selects field changes that get an inline review comment
+type ReviewRule struct {
+	Path    string   // Dot-notation glob, see diff.IgnoreRule
+	Kinds   []string // Only apply to documents of these kinds (all kinds if empty)
+	Message string
+}
+
+// ReviewComment is an inline comment on a line of a pull request diff
+type ReviewComment struct {
+	Path string
+	Line int
+	Side string
+	Body string
+}
+
+// BuildReviewComments creates review comments for the field changes matching rules.
+// paths converts source files to repository paths; changes whose document has no
+// repository path and changes without a known line are skipped. Deleted fields are
+// only commented on when paths maps the old document to the base of the pull request.
+func BuildReviewComments(result *diff.Result, rules []ReviewRule, paths PathMapper) ([]ReviewComment, error) {
+	matchers := make([]*diff.PathMatcher, 0, len(rules))
+	for _, rule := range rules {
+		matcher, err := diff.NewPathMatcher(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid review rule: %w", err)
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	var comments []ReviewComment
+	// oldPath is where deleted fields are commented on the LEFT side, or empty if the
+	// old document's lines do not refer to a version in the pull request diff
+	add := func(key string, doc parser.Document, oldPath string, changes []parser.Change) {
+		path := paths(doc.SourceFile, false)
+		if path == "" {
+			return
+		}
+		kind := parser.ExtractKey(doc.Content, "kind")
+
+		for _, change := range changes {
+			for i, rule := range rules {
+				if !matchers[i].Match(change.Path) || !kindMatches(rule.Kinds, kind) {
+					continue
+				}
+
+				// Deleted fields no longer exist in the new version, so they are
+				// commented on the line they were removed from
+				commentPath, line, side := path, change.Line, SideRight
+				if change.Type == parser.ChangeDeleted {
+					commentPath, line, side = oldPath, change.OldLine, SideLeft
+				}
+				if line == 0 || commentPath == "" {
+					break
+				}
+
+				comments = append(comments, ReviewComment{
+					Path: commentPath,
+					Line: line,
+					Side: side,
+					Body: reviewCommentBody(rule.Message, key, change),
+				})
+				break // One comment per change, from the first matching rule
+			}
+		}
+	}
+
+	for _, key := range result.ModifiedKeys() {
+		mod := result.Modified[key]
+		// GitHub shows both sides of a renamed file on its new path
+		oldPath := ""
+		if paths(mod.Old.SourceFile, true) != "" {
+			oldPath = paths(mod.New.SourceFile, false)
+		}
+		add(key, mod.New, oldPath, mod.Changes)
+	}
+	// Changes to moved documents are split across the old and new file
+	for _, key := range result.MovedKeys() {
+		moved := result.Moved[key]
+		add(key, moved.New, paths(moved.Old.SourceFile, true), moved.Changes)
+	}
+
+	return comments, nil
+}
+
+func kindMatches(kinds []string, kind string) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func reviewCommentBody(message, key string, change parser.Change) string {
+	var b strings.Builder
+	if message != "" {
+		b.WriteString(message)
+		b.WriteString("\n\n")
+	}
+	fmt.Fprintf(&b, "`%s`\n```\n%s\n```", key, change.String())
+	return b.String()
+}
+
+// CreateReview posts the comments as a single pull request review.
+// Comments on lines outside the pull request diff cannot be placed by GitHub and
+// are skipped, as are comments already posted by a previous run.
+func (c *Client) CreateReview(repo string, prNumber int, body string, comments []ReviewComment) error {
+	ctx := context.Background()
+	client := c.gh
+
+	owner, repoName, err := parseRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	lines, err := c.diffLines(ctx, owner, repoName, prNumber)
+	if err != nil {
+		return err
+	}
+	posted, err := c.postedReviewComments(ctx, owner, repoName, prNumber)
+	if err != nil {
+		return err
+	}
+
+	var draft []*github.DraftReviewComment
+	skipped := 0
+	for _, comment := range comments {
+		key := reviewCommentKey(comment.Path, comment.Line, comment.Side, comment.Body)
+		if posted[key] {
+			continue
+		}
+		if !lines[comment.Path][comment.Side][comment.Line] {
+			skipped++
+			continue
+		}
+		draft = append(draft, &github.DraftReviewComment{
+			Path: github.String(comment.Path),
+			Line: github.Int(comment.Line),
+			Side: github.String(comment.Side),
+			Body: github.String(comment.Body),
+		})
+	}
+
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d review comment(s) on lines outside the pull request diff\n", skipped)
+	}
+	if len(draft) == 0 {
+		fmt.Fprintf(os.Stderr, "✓ No new review comments to post on PR #%d\n", prNumber)
+		return nil
+	}
+
+	_, _, err = client.PullRequests.CreateReview(ctx, owner, repoName, prNumber, &github.PullRequestReviewRequest{
+		Body:     github.String(body),
+		Event:    github.String("COMMENT"),
+		Comments: draft,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create review: %w", classifyError(err))
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Posted review with %d comment(s) on PR #%d\n", len(draft), prNumber)
+	return nil
+}
+
+// diffLines returns the lines of each file that can be commented on, by path and side
+func (c *Client) diffLines(ctx context.Context, owner, repo string, prNumber int) (map[string]map[string]map[int]bool, error) {
+	lines := make(map[string]map[string]map[int]bool)
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := c.gh.PullRequests.ListFiles(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pull request files: %w", classifyError(err))
+		}
+		for _, file := range files {
+			lines[file.GetFilename()] = patchLines(file.GetPatch())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return lines, nil
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// patchLines returns the lines of a unified diff patch by side.
+// Context lines can be commented on from either side.
+func patchLines(patch string) map[string]map[int]bool {
+	lines := map[string]map[int]bool{
+		SideLeft:  {},
+		SideRight: {},
+	}
+
+	oldLine, newLine := 0, 0
+	for _, line := range strings.Split(patch, "\n") {
+		if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+			oldLine, _ = strconv.Atoi(m[1])
+			newLine, _ = strconv.Atoi(m[2])
+			continue
+		}
+		if oldLine == 0 && newLine == 0 {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+"):
+			lines[SideRight][newLine] = true
+			newLine++
+		case strings.HasPrefix(line, "-"):
+			lines[SideLeft][oldLine] = true
+			oldLine++
+		case strings.HasPrefix(line, "\\"):
+			// "\ No newline at end of file"
+		default:
+			lines[SideLeft][oldLine] = true
+			lines[SideRight][newLine] = true
+			oldLine++
+			newLine++
+		}
+	}
+
+	return lines
+}
+
+// postedReviewComments returns the keys of the review comments already on the pull request
+func (c *Client) postedReviewComments(ctx context.Context, owner, repo string, prNumber int) (map[string]bool, error) {
+	posted := make(map[string]bool)
+
+	opts := &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := c.gh.PullRequests.ListComments(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list review comments: %w", classifyError(err))
+		}
+		for _, comment := range comments {
+			posted[reviewCommentKey(comment.GetPath(), comment.GetLine(), comment.GetSide(), comment.GetBody())] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return posted, nil
+}
+
+func reviewCommentKey(path string, line int, side, body string) string {
+	return fmt.Sprintf("%s:%d:%s:%s", path, line, side, body)
+}
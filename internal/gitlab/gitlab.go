@@ -0,0 +1,364 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tyuhara/yamlcmt/internal/httputil"
+	"github.com/tyuhara/yamlcmt/internal/vcs"
+)
+
+// DefaultBaseURL is used when no GitLab instance is configured or detected
+const DefaultBaseURL = "https://gitlab.com"
+
+// defaultTimeout limits each API request
+const defaultTimeout = 30 * time.Second
+
+// ClientConfig holds the settings used to connect to the GitLab API
+type ClientConfig struct {
+	// Token is a personal, project or group access token with the api scope
+	Token string
+
+	// BaseURL is the URL of the GitLab instance (e.g. https://gitlab.example.com).
+	// DefaultBaseURL is used if empty.
+	BaseURL string
+
+	// CACertFile is a PEM bundle trusted in addition to the system certificates
+	CACertFile string
+
+	// Timeout limits each API request (30s if 0)
+	Timeout time.Duration
+}
+
+// Client performs yamlcmt's operations on GitLab merge requests
+type Client struct {
+	http    *http.Client
+	apiURL  string
+	token   string
+	timeout time.Duration
+}
+
+var _ vcs.Provider = (*Client)(nil)
+
+// NewClient creates a GitLab client from the given configuration
+func NewClient(cfg ClientConfig) (*Client, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("GitLab token is not set")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid GitLab URL: %w", err)
+	}
+
+	transport := http.DefaultTransport
+	if cfg.CACertFile != "" {
+		t, err := httputil.TransportWithCA(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		transport = t
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Client{
+		http:    &http.Client{Transport: transport},
+		apiURL:  strings.TrimSuffix(baseURL, "/") + "/api/v4",
+		token:   cfg.Token,
+		timeout: timeout,
+	}, nil
+}
+
+// note is a comment on a merge request
+type note struct {
+	ID     int64  `json:"id"`
+	Body   string `json:"body"`
+	System bool   `json:"system"`
+}
+
+// UpsertComment posts a merge request note embedding the marker for target,
+// handling notes from previous runs with the same marker according to mode.
+// GitLab cannot hide notes, so minimize collapses the previous notes instead.
+func (c *Client) UpsertComment(repo string, mrIID int, bodies []string, mode string, target string) error {
+	if err := vcs.ValidateCommentMode(mode); err != nil {
+		return err
+	}
+
+	marker := vcs.CommentMarker(target)
+	notesPath := fmt.Sprintf("%s/notes", mergeRequestPath(repo, mrIID))
+
+	var previous []note
+	if mode != "" && mode != vcs.CommentModeCreate {
+		var err error
+		previous, err = c.findNotes(notesPath, marker)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch mode {
+	case "", vcs.CommentModeCreate:
+		previous = nil
+	case vcs.CommentModeUpdate:
+		// The most recent notes are edited in place below; older parts of a
+		// comment previously split into more parts are deleted
+		if len(previous) > len(bodies) {
+			stale := previous[:len(previous)-len(bodies)]
+			previous = previous[len(previous)-len(bodies):]
+			for _, prev := range stale {
+				if !vcs.IsPart(prev.Body) {
+					continue
+				}
+				if err := c.do(http.MethodDelete, fmt.Sprintf("%s/%d", notesPath, prev.ID), nil, nil); err != nil {
+					return fmt.Errorf("failed to delete note %d: %w", prev.ID, err)
+				}
+			}
+		}
+	case vcs.CommentModeMinimize:
+		for _, prev := range previous {
+			collapsed := "<details><summary>Outdated yamlcmt comment</summary>\n\n" +
+				strings.ReplaceAll(prev.Body, marker, "") + "\n</details>\n"
+			if err := c.do(http.MethodPut, fmt.Sprintf("%s/%d", notesPath, prev.ID), map[string]string{"body": collapsed}, nil); err != nil {
+				return fmt.Errorf("failed to collapse note %d: %w", prev.ID, err)
+			}
+		}
+		if len(previous) > 0 {
+			fmt.Fprintf(os.Stderr, "✓ Collapsed %d previous GitLab note(s)\n", len(previous))
+		}
+		previous = nil
+	case vcs.CommentModeDelete:
+		for _, prev := range previous {
+			if err := c.do(http.MethodDelete, fmt.Sprintf("%s/%d", notesPath, prev.ID), nil, nil); err != nil {
+				return fmt.Errorf("failed to delete note %d: %w", prev.ID, err)
+			}
+		}
+		if len(previous) > 0 {
+			fmt.Fprintf(os.Stderr, "✓ Deleted %d previous GitLab note(s)\n", len(previous))
+		}
+		previous = nil
+	}
+
+	// In update mode, previous notes are reused in order; the rest are created.
+	// Notes have no URL of their own in the API, so parts are linked by anchor.
+	prevURL := ""
+	for i, body := range bodies {
+		if len(bodies) > 1 {
+			body = vcs.PartHeader(i, len(bodies), prevURL) + body
+		}
+		payload := map[string]string{"body": body + "\n" + marker + "\n"}
+
+		var posted note
+		if i < len(previous) {
+			if err := c.do(http.MethodPut, fmt.Sprintf("%s/%d", notesPath, previous[i].ID), payload, &posted); err != nil {
+				return fmt.Errorf("failed to update note: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "✓ Updated GitLab note on !%d\n", mrIID)
+		} else {
+			if err := c.do(http.MethodPost, notesPath, payload, &posted); err != nil {
+				return fmt.Errorf("failed to post note: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "✓ Posted GitLab note on !%d\n", mrIID)
+		}
+		prevURL = fmt.Sprintf("#note_%d", posted.ID)
+	}
+
+	return nil
+}
+
+// findNotes returns the merge request notes containing marker, oldest first
+func (c *Client) findNotes(notesPath, marker string) ([]note, error) {
+	var found []note
+
+	for page := 1; page != 0; {
+		var notes []note
+		next, err := c.get(fmt.Sprintf("%s?sort=asc&order_by=created_at&per_page=100&page=%d", notesPath, page), &notes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list notes: %w", err)
+		}
+		for _, n := range notes {
+			if !n.System && strings.Contains(n.Body, marker) {
+				found = append(found, n)
+			}
+		}
+		page = next
+	}
+
+	return found, nil
+}
+
+// ReconcileLabels makes the labels managed by yamlcmt on a merge request match labels.
+// Labels in labels that are missing are added, and labels in managed that are
+// present but not in labels are removed. Other labels are left untouched.
+func (c *Client) ReconcileLabels(repo string, mrIID int, labels []string, managed []string) error {
+	mrPath := mergeRequestPath(repo, mrIID)
+
+	var mr struct {
+		Labels []string `json:"labels"`
+	}
+	if _, err := c.get(mrPath, &mr); err != nil {
+		return fmt.Errorf("failed to get merge request labels: %w", err)
+	}
+
+	current := make(map[string]bool, len(mr.Labels))
+	for _, label := range mr.Labels {
+		current[label] = true
+	}
+	want := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		want[label] = true
+	}
+
+	var add, remove []string
+	for _, label := range labels {
+		if !current[label] {
+			add = append(add, label)
+		}
+	}
+	for _, label := range managed {
+		if current[label] && !want[label] {
+			remove = append(remove, label)
+		}
+	}
+
+	if len(add) == 0 && len(remove) == 0 {
+		fmt.Fprintf(os.Stderr, "✓ Labels on !%d are up to date\n", mrIID)
+		return nil
+	}
+
+	// A single update adds and removes labels without replacing the others
+	update := map[string]string{
+		"add_labels":    strings.Join(add, ","),
+		"remove_labels": strings.Join(remove, ","),
+	}
+	if err := c.do(http.MethodPut, mrPath, update, nil); err != nil {
+		return fmt.Errorf("failed to update merge request labels: %w", err)
+	}
+
+	for _, label := range remove {
+		fmt.Fprintf(os.Stderr, "✓ Removed GitLab label: %s\n", label)
+	}
+	for _, label := range add {
+		fmt.Fprintf(os.Stderr, "✓ Added GitLab label: %s\n", label)
+	}
+	return nil
+}
+
+// mergeRequestPath returns the API path of a merge request; the project path is URL-encoded
+func mergeRequestPath(repo string, mrIID int) string {
+	return fmt.Sprintf("/projects/%s/merge_requests/%d", url.PathEscape(repo), mrIID)
+}
+
+// get performs a GET request and returns the next page from the X-Next-Page header (0 if none)
+func (c *Client) get(path string, out interface{}) (int, error) {
+	resp, err := c.request(http.MethodGet, path, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("failed to decode GitLab response: %w", err)
+	}
+	next, _ := strconv.Atoi(resp.Header.Get("X-Next-Page"))
+	return next, nil
+}
+
+// do performs a request with a JSON body, decoding the response into out if it is not nil
+func (c *Client) do(method, path string, body interface{}, out interface{}) error {
+	resp, err := c.request(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode GitLab response: %w", err)
+	}
+	return nil
+}
+
+// request sends an authenticated API request and checks the response status
+func (c *Client) request(method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	req, err := http.NewRequestWithContext(ctx, method, c.apiURL+path, reader)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = httputil.CancelOnClose(resp.Body, cancel)
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, responseError(req, resp)
+	}
+	return resp, nil
+}
+
+// responseError describes a failed request, explaining the usual cause of its status
+func responseError(req *http.Request, resp *http.Response) error {
+	var payload struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	message := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &payload) == nil {
+		if payload.Message != nil {
+			message = fmt.Sprint(payload.Message)
+		} else if payload.Error != "" {
+			message = payload.Error
+		}
+	}
+
+	err := fmt.Errorf("%s %s: %s %s", req.Method, req.URL.Path, resp.Status, message)
+	switch code := resp.StatusCode; {
+	case code == http.StatusUnauthorized:
+		return fmt.Errorf("authentication failed: %w (check that the GitLab token is valid and not expired)", err)
+	case code == http.StatusForbidden:
+		return fmt.Errorf("permission denied: %w (the token needs the api scope and at least Reporter access)", err)
+	case code == http.StatusNotFound:
+		return fmt.Errorf("not found: %w (check the project path and merge request IID)", err)
+	case code == http.StatusTooManyRequests || code >= 500:
+		return fmt.Errorf("transient error: %w (try again later)", err)
+	}
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -15,7 +16,8 @@ type Document struct {
 	Content    map[string]interface{}
 	Raw        string
 	Key        string
-	SourceFile string // Source file path for tracking across multiple files
+	SourceFile string     // Source file path for tracking across multiple files
+	Node       *yaml.Node // Parsed node tree, used to keep the source key order
 }
 
 // ParseMultiDocYAML parses a YAML file that may contain multiple documents
@@ -25,12 +27,19 @@ func ParseMultiDocYAML(filename string) ([]Document, error) {
 		return nil, err
 	}
 
+	return ParseDocuments(data, "")
+}
+
+// ParseDocuments parses YAML content that may contain multiple documents.
+// sourceFile is recorded on each document and may be empty.
+// Empty documents (e.g. between two "---" separators) are skipped.
+func ParseDocuments(data []byte, sourceFile string) ([]Document, error) {
 	decoder := yaml.NewDecoder(bytes.NewReader(data))
 	var docs []Document
 
 	for {
-		var doc map[string]interface{}
-		err := decoder.Decode(&doc)
+		var node yaml.Node
+		err := decoder.Decode(&node)
 		if err == io.EOF {
 			break
 		}
@@ -38,15 +47,30 @@ func ParseMultiDocYAML(filename string) ([]Document, error) {
 			return nil, err
 		}
 
+		var doc map[string]interface{}
+		if err := node.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+
 		// Marshal back to YAML for display
 		raw, err := yaml.Marshal(doc)
 		if err != nil {
 			return nil, err
 		}
 
+		var root *yaml.Node
+		if len(node.Content) > 0 {
+			root = node.Content[0]
+		}
+
 		docs = append(docs, Document{
-			Content: doc,
-			Raw:     string(raw),
+			Content:    doc,
+			Raw:        string(raw),
+			SourceFile: sourceFile,
+			Node:       root,
 		})
 	}
 
@@ -116,44 +140,338 @@ func SplitLines(text string) []string {
 	return lines
 }
 
-// CompareValues recursively compares two values and returns a formatted diff
-func CompareValues(path string, oldVal, newVal interface{}) []string {
-	var diffs []string
+// ChangeType describes how a value changed between two documents
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeDeleted  ChangeType = "deleted"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change represents a single field-level difference between two documents
+type Change struct {
+	Type ChangeType
+	Path string
+	Old  interface{}
+	New  interface{}
+
+	// Line and Column locate the change in the new document's source
+	// (the parent for deleted fields). They are 0 if unknown.
+	Line   int
+	Column int
+
+	// OldLine locates the change in the old document's source
+	// (the parent for added fields). It is 0 if unknown.
+	OldLine int
+}
+
+// String formats the change as a +/-/~ diff line
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %v", c.Path, c.New)
+	case ChangeDeleted:
+		return fmt.Sprintf("- %s: %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %v → %v", c.Path, c.Old, c.New)
+	}
+}
+
+// CompareOptions controls how CompareValues matches values
+type CompareOptions struct {
+	// ListKeys maps a list path (without element selectors) to the field used
+	// to match its elements, e.g. "spec.template.spec.containers" → "name".
+	// Lists without a merge key are compared element-by-element by index.
+	ListKeys map[string]string
+
+	// Ignore reports whether changes at a path (and below it) should be skipped
+	Ignore func(path string) bool
+}
+
+// ignored reports whether the path is excluded by the Ignore option
+func (o CompareOptions) ignored(path string) bool {
+	return path != "" && o.Ignore != nil && o.Ignore(path)
+}
+
+// CompareDocuments compares two documents and returns the changes between them.
+// Changes are ordered as the keys appear in the source documents.
+func CompareDocuments(oldDoc, newDoc Document, opts CompareOptions) []Change {
+	return compareValues("", oldDoc.Content, newDoc.Content, oldDoc.Node, newDoc.Node, opts)
+}
+
+// CompareValues recursively compares two values and returns the changes between them.
+// Map keys are compared in sorted order.
+// List elements are addressed as path[0], or path[name=app] when a merge key
+// is configured for the list.
+func CompareValues(path string, oldVal, newVal interface{}, opts CompareOptions) []Change {
+	return compareValues(path, oldVal, newVal, nil, nil, opts)
+}
+
+// compareValues compares two values alongside their nodes, which may be nil
+func compareValues(path string, oldVal, newVal interface{}, oldNode, newNode *yaml.Node, opts CompareOptions) []Change {
+	if opts.ignored(path) {
+		return nil
+	}
+
+	var diffs []Change
 
 	oldMap, oldIsMap := oldVal.(map[string]interface{})
 	newMap, newIsMap := newVal.(map[string]interface{})
+	oldList, oldIsList := oldVal.([]interface{})
+	newList, newIsList := newVal.([]interface{})
 
 	if oldIsMap && newIsMap {
-		// Both are maps - recurse
-		allKeys := make(map[string]bool)
-		for k := range oldMap {
-			allKeys[k] = true
-		}
-		for k := range newMap {
-			allKeys[k] = true
-		}
-
-		for key := range allKeys {
+		// Both are maps - recurse in source order
+		for _, key := range orderedKeys(oldMap, newMap, oldNode, newNode) {
 			newPath := path + "." + key
 			if path == "" {
 				newPath = key
 			}
 
+			if opts.ignored(newPath) {
+				continue
+			}
+
 			oldV, oldExists := oldMap[key]
 			newV, newExists := newMap[key]
 
+			oldKeyNode, oldValueNode := mappingEntry(oldNode, key)
+			newKeyNode, newValueNode := mappingEntry(newNode, key)
+
 			if !oldExists && newExists {
-				diffs = append(diffs, fmt.Sprintf("+ %s: %v", newPath, newV))
+				diffs = append(diffs, positioned(Change{Type: ChangeAdded, Path: newPath, New: newV}, newKeyNode, oldNode))
 			} else if oldExists && !newExists {
-				diffs = append(diffs, fmt.Sprintf("- %s: %v", newPath, oldV))
+				diffs = append(diffs, positioned(Change{Type: ChangeDeleted, Path: newPath, Old: oldV}, newNode, oldKeyNode))
 			} else if oldExists && newExists {
-				subDiffs := CompareValues(newPath, oldV, newV)
+				subDiffs := compareValues(newPath, oldV, newV, oldValueNode, newValueNode, opts)
 				diffs = append(diffs, subDiffs...)
 			}
 		}
+	} else if oldIsList && newIsList {
+		// Both are lists - compare element-by-element
+		if field, ok := opts.ListKeys[stripSelectors(path)]; ok {
+			if keyed, ok := compareKeyedLists(path, field, oldList, newList, oldNode, newNode, opts); ok {
+				return keyed
+			}
+		}
+		diffs = compareIndexedLists(path, oldList, newList, oldNode, newNode, opts)
 	} else if fmt.Sprintf("%v", oldVal) != fmt.Sprintf("%v", newVal) {
-		diffs = append(diffs, fmt.Sprintf("~ %s: %v → %v", path, oldVal, newVal))
+		diffs = append(diffs, positioned(Change{Type: ChangeModified, Path: path, Old: oldVal, New: newVal}, newNode, oldNode))
 	}
 
 	return diffs
 }
+
+// compareIndexedLists compares list elements at the same position
+func compareIndexedLists(path string, oldList, newList []interface{}, oldNode, newNode *yaml.Node, opts CompareOptions) []Change {
+	var diffs []Change
+
+	for i := 0; i < len(oldList) || i < len(newList); i++ {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		if opts.ignored(elemPath) {
+			continue
+		}
+
+		switch {
+		case i >= len(oldList):
+			diffs = append(diffs, positioned(Change{Type: ChangeAdded, Path: elemPath, New: newList[i]}, sequenceItem(newNode, i), oldNode))
+		case i >= len(newList):
+			diffs = append(diffs, positioned(Change{Type: ChangeDeleted, Path: elemPath, Old: oldList[i]}, newNode, sequenceItem(oldNode, i)))
+		default:
+			diffs = append(diffs, compareValues(elemPath, oldList[i], newList[i], sequenceItem(oldNode, i), sequenceItem(newNode, i), opts)...)
+		}
+	}
+
+	return diffs
+}
+
+// compareKeyedLists matches list elements by the value of their merge key field,
+// so reordering elements is not reported as a change.
+// It returns false if any element lacks the field or a value is duplicated,
+// in which case the caller falls back to index-based comparison.
+func compareKeyedLists(path, field string, oldList, newList []interface{}, oldNode, newNode *yaml.Node, opts CompareOptions) ([]Change, bool) {
+	oldKeys, ok := listKeyValues(oldList, field)
+	if !ok {
+		return nil, false
+	}
+	newKeys, ok := listKeyValues(newList, field)
+	if !ok {
+		return nil, false
+	}
+
+	newIndex := make(map[string]int, len(newKeys))
+	for i, k := range newKeys {
+		newIndex[k] = i
+	}
+	oldIndex := make(map[string]int, len(oldKeys))
+	for i, k := range oldKeys {
+		oldIndex[k] = i
+	}
+
+	var diffs []Change
+	for i, k := range oldKeys {
+		elemPath := fmt.Sprintf("%s[%s=%s]", path, field, k)
+		if opts.ignored(elemPath) {
+			continue
+		}
+
+		if j, exists := newIndex[k]; exists {
+			diffs = append(diffs, compareValues(elemPath, oldList[i], newList[j], sequenceItem(oldNode, i), sequenceItem(newNode, j), opts)...)
+		} else {
+			diffs = append(diffs, positioned(Change{Type: ChangeDeleted, Path: elemPath, Old: oldList[i]}, newNode, sequenceItem(oldNode, i)))
+		}
+	}
+	for j, k := range newKeys {
+		if _, exists := oldIndex[k]; !exists {
+			elemPath := fmt.Sprintf("%s[%s=%s]", path, field, k)
+			if opts.ignored(elemPath) {
+				continue
+			}
+			diffs = append(diffs, positioned(Change{Type: ChangeAdded, Path: elemPath, New: newList[j]}, sequenceItem(newNode, j), oldNode))
+		}
+	}
+
+	return diffs, true
+}
+
+// orderedKeys returns the union of the keys of two maps.
+// Keys are ordered as they appear in the new node, followed by keys only found
+// in the old node; any remaining keys (e.g. without nodes) are appended sorted.
+func orderedKeys(oldMap, newMap map[string]interface{}, oldNode, newNode *yaml.Node) []string {
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	seen := make(map[string]bool, len(oldMap)+len(newMap))
+
+	add := func(key string) {
+		if seen[key] {
+			return
+		}
+		_, inOld := oldMap[key]
+		_, inNew := newMap[key]
+		if inOld || inNew {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range mappingKeys(newNode) {
+		add(key)
+	}
+	for _, key := range mappingKeys(oldNode) {
+		add(key)
+	}
+
+	var rest []string
+	for _, m := range []map[string]interface{}{oldMap, newMap} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				rest = append(rest, key)
+			}
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+// mappingKeys returns the keys of a mapping node in source order
+func mappingKeys(node *yaml.Node) []string {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	keys := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// mappingEntry returns the key and value nodes for key in a mapping node, or nils
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// positioned sets the source positions of a change from its nodes, which may be nil
+func positioned(change Change, newNode, oldNode *yaml.Node) Change {
+	if newNode != nil {
+		change.Line = newNode.Line
+		change.Column = newNode.Column
+	}
+	if oldNode != nil {
+		change.OldLine = oldNode.Line
+	}
+	return change
+}
+
+// sequenceItem returns the i-th item of a sequence node, or nil
+func sequenceItem(node *yaml.Node, i int) *yaml.Node {
+	node = resolveAlias(node)
+	if node == nil || node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+		return nil
+	}
+	return node.Content[i]
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// listKeyValues returns the merge key value of each list element
+func listKeyValues(list []interface{}, field string) ([]string, bool) {
+	keys := make([]string, 0, len(list))
+	seen := make(map[string]bool, len(list))
+
+	for _, elem := range list {
+		m, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok := m[field]
+		if !ok || v == nil {
+			return nil, false
+		}
+		k := fmt.Sprintf("%v", v)
+		if seen[k] {
+			return nil, false
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+
+	return keys, true
+}
+
+// stripSelectors removes list element selectors such as [0] or [name=app] from a path
+func stripSelectors(path string) string {
+	var b strings.Builder
+	depth := 0
+
+	for _, char := range path {
+		switch {
+		case char == '[':
+			depth++
+		case char == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(char)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,168 @@
+package pathfilter
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Filter selects file paths with include and exclude lists of gitignore-style patterns.
+//
+// A pattern without a slash matches a file or directory name at any depth, while a
+// pattern with a leading or inner slash is relative to the root. "*" and "?" match
+// within a path segment, "**" across segments, and a trailing slash only matches
+// directories. Matching a directory also matches every file below it.
+// Within a list the last matching pattern wins, and a leading "!" negates a pattern,
+// e.g. ["charts/", "!charts/*/templates/"].
+type Filter struct {
+	include []pattern
+	exclude []pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// New compiles the include and exclude patterns. Empty patterns and patterns
+// starting with "#" are skipped.
+func New(include, exclude []string) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	if f.include, err = compileAll(include); err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	if f.exclude, err = compileAll(exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	return f, nil
+}
+
+// Match reports whether a path is selected: it matches the include patterns (if any)
+// and does not match the exclude patterns
+func (f *Filter) Match(p string) bool {
+	p = path.Clean(filepath.ToSlash(p))
+	if len(f.include) > 0 && !matchList(f.include, p) {
+		return false
+	}
+	return !matchList(f.exclude, p)
+}
+
+// matchList returns the result of the last pattern matching p, or false if none does
+func matchList(patterns []pattern, p string) bool {
+	matched := false
+	for _, pat := range patterns {
+		if pat.match(p) {
+			matched = !pat.negate
+		}
+	}
+	return matched
+}
+
+// match reports whether the pattern matches p or one of its parent directories
+func (pat pattern) match(p string) bool {
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' && pat.re.MatchString(p[:i]) {
+			return true
+		}
+	}
+	return !pat.dirOnly && pat.re.MatchString(p)
+}
+
+func compileAll(patterns []string) ([]pattern, error) {
+	var compiled []pattern
+	for _, s := range patterns {
+		if strings.TrimSpace(s) == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		pat, err := compile(s)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, pat)
+	}
+	return compiled, nil
+}
+
+func compile(s string) (pattern, error) {
+	var pat pattern
+	glob := strings.TrimRight(s, " ")
+
+	if strings.HasPrefix(glob, "!") {
+		pat.negate = true
+		glob = glob[1:]
+	} else if strings.HasPrefix(glob, `\!`) || strings.HasPrefix(glob, `\#`) {
+		glob = glob[1:]
+	}
+	if strings.HasSuffix(glob, "/") {
+		pat.dirOnly = true
+		glob = strings.TrimSuffix(glob, "/")
+	}
+	if glob == "" {
+		return pattern{}, fmt.Errorf("%q matches nothing", s)
+	}
+
+	if strings.HasPrefix(glob, "./") {
+		glob = glob[1:]
+	}
+
+	// Patterns with a slash are relative to the root, others match at any depth
+	anchored := strings.Contains(glob, "/")
+	glob = strings.TrimPrefix(glob, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	b.WriteString(globToRegexp(glob))
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return pattern{}, fmt.Errorf("%q: %w", s, err)
+	}
+	pat.re = re
+	return pat, nil
+}
+
+// globToRegexp converts the segments of a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Zero or more directories
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
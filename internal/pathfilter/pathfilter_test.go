@@ -0,0 +1,81 @@
+package pathfilter
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		want    bool
+	}{
+		{name: "no patterns", path: "k8s/app.yaml", want: true},
+
+		// Unanchored patterns match a name at any depth
+		{name: "unanchored name at root", exclude: []string{"app.yaml"}, path: "app.yaml", want: false},
+		{name: "unanchored name nested", exclude: []string{"app.yaml"}, path: "k8s/prod/app.yaml", want: false},
+		{name: "unanchored glob", exclude: []string{"*.gen.yaml"}, path: "k8s/crd.gen.yaml", want: false},
+		{name: "unanchored glob other file", exclude: []string{"*.gen.yaml"}, path: "k8s/crd.yaml", want: true},
+		{name: "unanchored directory", exclude: []string{"generated"}, path: "k8s/generated/crd.yaml", want: false},
+
+		// Patterns with a slash are relative to the root
+		{name: "anchored at root", exclude: []string{"k8s/app.yaml"}, path: "k8s/app.yaml", want: false},
+		{name: "anchored not nested", exclude: []string{"k8s/app.yaml"}, path: "other/k8s/app.yaml", want: true},
+		{name: "leading slash", exclude: []string{"/app.yaml"}, path: "app.yaml", want: false},
+		{name: "leading slash not nested", exclude: []string{"/app.yaml"}, path: "k8s/app.yaml", want: true},
+		{name: "leading dot slash", include: []string{"./k8s"}, path: "k8s/app.yaml", want: true},
+		{name: "star within a segment", exclude: []string{"k8s/*.yaml"}, path: "k8s/app.yaml", want: false},
+		{name: "star not below the segment", exclude: []string{"k8s/*.yaml"}, path: "k8s/prod/app.yaml", want: true},
+		{name: "star does not cross segments", include: []string{"k8s/*/app.yaml"}, path: "k8s/a/b/app.yaml", want: false},
+		{name: "question mark", include: []string{"k8s/app-?.yaml"}, path: "k8s/app-1.yaml", want: true},
+		{name: "character class", include: []string{"k8s/app-[0-9].yaml"}, path: "k8s/app-x.yaml", want: false},
+		{name: "negated character class", include: []string{"k8s/app-[!0-9].yaml"}, path: "k8s/app-x.yaml", want: true},
+
+		// "**" matches across segments
+		{name: "leading double star", include: []string{"**/prod/*.yaml"}, path: "a/b/prod/app.yaml", want: true},
+		{name: "leading double star at root", include: []string{"**/prod/*.yaml"}, path: "prod/app.yaml", want: true},
+		{name: "inner double star", include: []string{"k8s/**/app.yaml"}, path: "k8s/app.yaml", want: true},
+		{name: "inner double star nested", include: []string{"k8s/**/app.yaml"}, path: "k8s/a/b/app.yaml", want: true},
+		{name: "trailing double star", include: []string{"k8s/**"}, path: "k8s/a/b/app.yaml", want: true},
+		{name: "trailing double star other root", include: []string{"k8s/**"}, path: "other/app.yaml", want: false},
+
+		// A trailing slash only matches directories
+		{name: "directory pattern", exclude: []string{"charts/"}, path: "charts/app/values.yaml", want: false},
+		{name: "directory pattern nested", exclude: []string{"charts/"}, path: "k8s/charts/values.yaml", want: false},
+		{name: "directory pattern on file", exclude: []string{"charts/"}, path: "k8s/charts", want: true},
+
+		// The last matching pattern wins, "!" negates
+		{name: "negation", exclude: []string{"charts/", "!charts/*/templates/"}, path: "charts/app/templates/deploy.yaml", want: true},
+		{name: "negation not matching", exclude: []string{"charts/", "!charts/*/templates/"}, path: "charts/app/values.yaml", want: false},
+		{name: "last match wins", exclude: []string{"!*.yaml", "*.yaml"}, path: "app.yaml", want: false},
+		{name: "negation only", exclude: []string{"!app.yaml"}, path: "app.yaml", want: true},
+		{name: "escaped exclamation mark", exclude: []string{`\!app.yaml`}, path: "!app.yaml", want: false},
+
+		// Include and exclude combined
+		{name: "not included", include: []string{"k8s/"}, path: "docs/app.yaml", want: false},
+		{name: "included", include: []string{"k8s/"}, path: "k8s/app.yaml", want: true},
+		{name: "included and excluded", include: []string{"k8s/"}, exclude: []string{"*.gen.yaml"}, path: "k8s/crd.gen.yaml", want: false},
+		{name: "comments and blank lines are skipped", include: []string{"# k8s/", "  ", "k8s/"}, path: "k8s/app.yaml", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) with include %q and exclude %q = %v, want %v", tt.path, tt.include, tt.exclude, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"/", "!"} {
+		if _, err := New([]string{pattern}, nil); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", pattern)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package vcs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var _ Provider = (*DryRun)(nil)
+
+// DryRun prints the comments and labels a provider would publish instead of calling its API.
+// It needs no credentials, so it cannot see the current state of the pull or merge request.
+type DryRun struct {
+	w        io.Writer
+	provider string
+}
+
+// NewDryRun creates a DryRun for provider (GitHub or GitLab) that prints to w
+func NewDryRun(w io.Writer, provider string) *DryRun {
+	return &DryRun{w: w, provider: provider}
+}
+
+// UpsertComment prints the comments that would be posted
+func (d *DryRun) UpsertComment(repo string, number int, bodies []string, mode string, target string) error {
+	if err := ValidateCommentMode(mode); err != nil {
+		return err
+	}
+	if mode == "" {
+		mode = CommentModeCreate
+	}
+
+	// GitLab cannot hide notes, so they are collapsed instead
+	comments, minimize := "comment(s)", "minimize"
+	if d.provider == GitLab {
+		comments, minimize = "note(s)", "collapse"
+	}
+
+	marker := CommentMarker(target)
+	ref := d.Ref(repo, number)
+	switch mode {
+	case CommentModeCreate:
+		d.Printf("Would post %d %s on %s\n", len(bodies), comments, ref)
+	case CommentModeUpdate:
+		d.Printf("Would update the latest %s with %s on %s, or post %d new %s\n", comments, marker, ref, len(bodies), comments)
+	case CommentModeMinimize:
+		d.Printf("Would %s previous %s with %s on %s and post %d %s\n", minimize, comments, marker, ref, len(bodies), comments)
+	case CommentModeDelete:
+		d.Printf("Would delete previous %s with %s on %s and post %d %s\n", comments, marker, ref, len(bodies), comments)
+	}
+
+	for i, body := range bodies {
+		if len(bodies) > 1 {
+			body = PartHeader(i, len(bodies), "") + body
+		}
+		d.Section(fmt.Sprintf("comment %d/%d (%d characters)", i+1, len(bodies), len(body)), body+"\n"+marker+"\n")
+	}
+	return nil
+}
+
+// ReconcileLabels prints the labels that would be added and removed
+func (d *DryRun) ReconcileLabels(repo string, number int, labels []string, managed []string) error {
+	want := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		want[label] = true
+	}
+	var stale []string
+	for _, label := range managed {
+		if !want[label] {
+			stale = append(stale, label)
+		}
+	}
+
+	d.Printf("Would add label(s) to %s: %s\n", d.Ref(repo, number), list(labels))
+	d.Printf("Would remove label(s) if present: %s\n", list(stale))
+	return nil
+}
+
+// Printf prints a line prefixed with "[dry-run]"
+func (d *DryRun) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(d.w, "[dry-run] "+format, args...)
+}
+
+// Writef prints without a prefix, e.g. details of the previous line
+func (d *DryRun) Writef(format string, args ...interface{}) {
+	fmt.Fprintf(d.w, format, args...)
+}
+
+// Section prints a titled block of text
+func (d *DryRun) Section(title, text string) {
+	fmt.Fprintf(d.w, "----- %s -----\n%s", title, text)
+	if !strings.HasSuffix(text, "\n") {
+		fmt.Fprintln(d.w)
+	}
+	fmt.Fprintf(d.w, "-----\n")
+}
+
+// Ref formats a pull request (owner/repo#1) or merge request (group/project!1) reference
+func (d *DryRun) Ref(repo string, number int) string {
+	if number == 0 {
+		if d.provider == GitLab {
+			return repo + " (MR not detected)"
+		}
+		return repo + " (PR not detected)"
+	}
+	if d.provider == GitLab {
+		return fmt.Sprintf("%s!%d", repo, number)
+	}
+	return fmt.Sprintf("%s#%d", repo, number)
+}
+
+func list(items []string) string {
+	if len(items) == 0 {
+		return "(none)"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,64 @@
+package vcs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Provider names used in the config and for auto-detection
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// Provider publishes comparison results to a code review:
+// a GitHub pull request or a GitLab merge request.
+// repo is "owner/repo" on GitHub and the project path on GitLab.
+type Provider interface {
+	// UpsertComment posts bodies as consecutive comments embedding the marker
+	// for target, handling comments from previous runs according to mode
+	UpsertComment(repo string, number int, bodies []string, mode string, target string) error
+
+	// ReconcileLabels adds the missing labels and removes the managed labels
+	// that are present but not in labels
+	ReconcileLabels(repo string, number int, labels []string, managed []string) error
+}
+
+// Comment modes for UpsertComment
+const (
+	CommentModeCreate   = "create"   // Always post a new comment
+	CommentModeUpdate   = "update"   // Edit the previous yamlcmt comment, or post a new one
+	CommentModeMinimize = "minimize" // Hide previous yamlcmt comments as outdated, then post a new one
+	CommentModeDelete   = "delete"   // Delete previous yamlcmt comments, then post a new one
+)
+
+// ValidateCommentMode returns an error for unknown comment modes ("" is create)
+func ValidateCommentMode(mode string) error {
+	switch mode {
+	case "", CommentModeCreate, CommentModeUpdate, CommentModeMinimize, CommentModeDelete:
+		return nil
+	}
+	return fmt.Errorf("unknown comment mode: %s (expected: create, update, minimize or delete)", mode)
+}
+
+// CommentMarker returns the hidden HTML marker that identifies yamlcmt comments for a target
+func CommentMarker(target string) string {
+	if target == "" {
+		return "<!-- yamlcmt -->"
+	}
+	return fmt.Sprintf("<!-- yamlcmt:%s -->", target)
+}
+
+// PartHeader introduces part i (from 0) of a comment split into n parts,
+// linking to the previous part if its URL is known
+func PartHeader(i, n int, prevURL string) string {
+	if i == 0 || prevURL == "" {
+		return fmt.Sprintf("_Part %d of %d_\n\n", i+1, n)
+	}
+	return fmt.Sprintf("_Part %d of %d, continued from [part %d](%s)_\n\n", i+1, n, i, prevURL)
+}
+
+// IsPart reports whether a comment body is a part of a split comment
+func IsPart(body string) bool {
+	return strings.HasPrefix(body, "_Part ")
+}